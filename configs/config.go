@@ -0,0 +1,682 @@
+package configs
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"ticket-booking/configs/logs"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the whole application configuration. It is resolved from, in increasing order of precedence:
+// the defaults below, an optional YAML or TOML file, environment variables and command line flags.
+// Every field with an env tag also gets a flag named after it, e.g. DB_HOST becomes --db-host.
+type Config struct {
+	Server   ServerConfig   `yaml:"server" toml:"server"`
+	App      AppConfig      `yaml:"app" toml:"app"`
+	Database DatabaseConfig `yaml:"database" toml:"database"`
+	Log      LogConfig      `yaml:"log" toml:"log"`
+	Tracing  TracingConfig  `yaml:"tracing" toml:"tracing"`
+	JWT      JWTConfig      `yaml:"jwt" toml:"jwt"`
+	Argon2   Argon2Config   `yaml:"argon2" toml:"argon2"`
+	SignIn   SignInConfig   `yaml:"sign_in" toml:"sign_in"`
+	TOTP     TOTPConfig     `yaml:"totp" toml:"totp"`
+	Mailer   MailerConfig   `yaml:"mailer" toml:"mailer"`
+	OIDC     OIDCConfig     `yaml:"oidc" toml:"oidc"`
+	WebAuthn WebAuthnConfig `yaml:"webauthn" toml:"webauthn"`
+
+	// PrintConfig is only set from the command line: print the effective configuration and exit.
+	PrintConfig bool `yaml:"-" toml:"-"`
+	// Args are the command line arguments left after the flags, e.g. "migrate up".
+	Args []string `yaml:"-" toml:"-"`
+}
+
+type ServerConfig struct {
+	Port         int           `yaml:"port" toml:"port" env:"PORT"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	// IdleTimeout also bounds how long an idle keep-alive connection can delay shutdown.
+	IdleTimeout time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	// ShutdownTimeout bounds each shutdown phase: draining requests and stopping the background workers.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+}
+
+type AppConfig struct {
+	// BaseURL is the public address of the frontend, used in the links sent by email.
+	BaseURL string `yaml:"base_url" toml:"base_url" env:"APP_BASE_URL"`
+}
+
+type DatabaseConfig struct {
+	Host     string `yaml:"host" toml:"host" env:"DB_HOST"`
+	Port     int    `yaml:"port" toml:"port" env:"DB_PORT"`
+	User     string `yaml:"user" toml:"user" env:"DB_USER"`
+	Password string `yaml:"password" toml:"password" env:"DB_PASSWORD" secret:"true"`
+	Name     string `yaml:"name" toml:"name" env:"DB_NAME"`
+	SSLMode  string `yaml:"sslmode" toml:"sslmode" env:"DB_SSLMODE"`
+	// Pool configures the connections to the primary, which serves every write.
+	Pool PoolConfig `yaml:"pool" toml:"pool" envPrefix:"DB_"`
+	// Replicas are the PostgreSQL URLs of the read replicas, used in turn. Without replicas, reads go to the primary.
+	Replicas []string `yaml:"replicas" toml:"replicas" env:"DB_REPLICA_URLS" secret:"true"`
+	// ReplicaPool configures the connections to each replica.
+	ReplicaPool PoolConfig `yaml:"replica_pool" toml:"replica_pool" envPrefix:"DB_REPLICA_"`
+	// ReadYourWritesWindow sends the reads of an account to the primary for this long after it wrote,
+	// so it does not miss its own changes while the replicas catch up. Zero disables it. The instance
+	// that served the write remembers it for every client of the account; other instances only honour
+	// the window for clients returning the write marker (the last_write cookie or X-Last-Write header).
+	ReadYourWritesWindow time.Duration `yaml:"read_your_writes_window" toml:"read_your_writes_window" env:"DB_READ_YOUR_WRITES_WINDOW"`
+	// AutoMigrate applies the pending migrations at startup, instead of running "migrate up" separately.
+	AutoMigrate bool `yaml:"auto_migrate" toml:"auto_migrate" env:"DB_AUTO_MIGRATE"`
+	// ConnectTimeout is how long startup keeps retrying to reach the databases.
+	ConnectTimeout time.Duration `yaml:"connect_timeout" toml:"connect_timeout" env:"DB_CONNECT_TIMEOUT"`
+	// MaxRetries and RetryBackoff apply to statements failing with a transient error that are safe to run again.
+	MaxRetries   int           `yaml:"max_retries" toml:"max_retries" env:"DB_MAX_RETRIES"`
+	RetryBackoff time.Duration `yaml:"retry_backoff" toml:"retry_backoff" env:"DB_RETRY_BACKOFF"`
+	// The circuit breaker opens after BreakerThreshold consecutive connection errors and probes the
+	// database again after BreakerCooldown; meanwhile requests are answered with 503.
+	BreakerThreshold    int           `yaml:"breaker_threshold" toml:"breaker_threshold" env:"DB_BREAKER_THRESHOLD"`
+	BreakerCooldown     time.Duration `yaml:"breaker_cooldown" toml:"breaker_cooldown" env:"DB_BREAKER_COOLDOWN"`
+	HealthCheckInterval time.Duration `yaml:"health_check_interval" toml:"health_check_interval" env:"DB_HEALTH_CHECK_INTERVAL"`
+}
+
+type PoolConfig struct {
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns" env:"MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns" env:"MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime" env:"CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time" env:"CONN_MAX_IDLE_TIME"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level" toml:"level" env:"LOG_LEVEL"`
+	// Sinks are any of "stdout" (JSON), "console" (human readable, on stdout) and "file" (JSON).
+	Sinks []string `yaml:"sinks" toml:"sinks" env:"LOG_SINKS"`
+	File  string   `yaml:"file" toml:"file" env:"LOG_FILE"`
+	// The file is rotated when it reaches MaxSize megabytes, and every RotateInterval unless it is zero.
+	MaxSize        int           `yaml:"max_size" toml:"max_size" env:"LOG_MAX_SIZE"`
+	RotateInterval time.Duration `yaml:"rotate_interval" toml:"rotate_interval" env:"LOG_ROTATE_INTERVAL"`
+	// Rotated files are deleted after MaxAge, rounded up to whole days, and beyond the MaxBackups newest;
+	// zero keeps them.
+	MaxAge     time.Duration `yaml:"max_age" toml:"max_age" env:"LOG_MAX_AGE"`
+	MaxBackups int           `yaml:"max_backups" toml:"max_backups" env:"LOG_MAX_BACKUPS"`
+	Compress   bool          `yaml:"compress" toml:"compress" env:"LOG_COMPRESS"`
+}
+
+type TracingConfig struct {
+	// Exporter is "none", "stdout", "file" (one JSON span per line, for offline use) or "otlp".
+	Exporter string `yaml:"exporter" toml:"exporter" env:"TRACING_EXPORTER"`
+	File     string `yaml:"file" toml:"file" env:"TRACING_FILE"`
+	// OTLPEndpoint is the OTLP/HTTP collector URL; empty falls back to the OTEL_EXPORTER_OTLP_* variables.
+	OTLPEndpoint string `yaml:"otlp_endpoint" toml:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT"`
+	// SampleRatio is the share of traces started here that are recorded; traces started by a caller
+	// follow its sampling decision.
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+	ServiceName string  `yaml:"service_name" toml:"service_name" env:"TRACING_SERVICE_NAME"`
+}
+
+type JWTConfig struct {
+	// KeysDir holds the PEM signing keys; without it tokens are signed with Secret, or an ephemeral key.
+	KeysDir            string        `yaml:"keys_dir" toml:"keys_dir" env:"JWT_KEYS_DIR"`
+	SigningKeyID       string        `yaml:"signing_key_id" toml:"signing_key_id" env:"JWT_SIGNING_KEY_ID"`
+	Secret             string        `yaml:"secret" toml:"secret" env:"JWT_SECRET" secret:"true"`
+	Issuer             string        `yaml:"issuer" toml:"issuer" env:"JWT_ISSUER"`
+	Audience           string        `yaml:"audience" toml:"audience" env:"JWT_AUDIENCE"`
+	Expiry             time.Duration `yaml:"expiry" toml:"expiry" env:"JWT_EXPIRY"`
+	RefreshExpiry      time.Duration `yaml:"refresh_expiry" toml:"refresh_expiry" env:"JWT_REFRESH_EXPIRY"`
+	RevocationCacheTTL time.Duration `yaml:"revocation_cache_ttl" toml:"revocation_cache_ttl" env:"JWT_REVOCATION_CACHE_TTL"`
+}
+
+type Argon2Config struct {
+	Memory      uint32 `yaml:"memory" toml:"memory" env:"ARGON2_MEMORY"`
+	Iterations  uint32 `yaml:"iterations" toml:"iterations" env:"ARGON2_ITERATIONS"`
+	Parallelism uint8  `yaml:"parallelism" toml:"parallelism" env:"ARGON2_PARALLELISM"`
+}
+
+type SignInConfig struct {
+	MaxFailures   int           `yaml:"max_failures" toml:"max_failures" env:"SIGNIN_MAX_FAILURES"`
+	IPMaxFailures int           `yaml:"ip_max_failures" toml:"ip_max_failures" env:"SIGNIN_IP_MAX_FAILURES"`
+	IPWindow      time.Duration `yaml:"ip_window" toml:"ip_window" env:"SIGNIN_IP_WINDOW"`
+	Lockout       time.Duration `yaml:"lockout" toml:"lockout" env:"SIGNIN_LOCKOUT"`
+	MaxLockout    time.Duration `yaml:"max_lockout" toml:"max_lockout" env:"SIGNIN_MAX_LOCKOUT"`
+}
+
+type TOTPConfig struct {
+	Issuer string `yaml:"issuer" toml:"issuer" env:"TOTP_ISSUER"`
+}
+
+type MailerConfig struct {
+	// Driver is "smtp" or "outbox", which writes the emails to OutboxDir for local development.
+	Driver    string     `yaml:"driver" toml:"driver" env:"MAILER_DRIVER"`
+	From      string     `yaml:"from" toml:"from" env:"MAILER_FROM"`
+	OutboxDir string     `yaml:"outbox_dir" toml:"outbox_dir" env:"MAILER_OUTBOX_DIR"`
+	SMTP      SMTPConfig `yaml:"smtp" toml:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host" toml:"host" env:"SMTP_HOST"`
+	Port     int    `yaml:"port" toml:"port" env:"SMTP_PORT"`
+	Username string `yaml:"username" toml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" toml:"password" env:"SMTP_PASSWORD" secret:"true"`
+}
+
+type OIDCConfig struct {
+	// RedirectURL is the callback registered at the providers, "{provider}" being replaced by the name.
+	RedirectURL string `yaml:"redirect_url" toml:"redirect_url" env:"OIDC_REDIRECT_URL"`
+	// Providers come from the file, or from OIDC_PROVIDERS (e.g. "google,microsoft") with each provider
+	// configured by OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET and OIDC_<NAME>_SCOPES.
+	Providers []OIDCProviderConfig `yaml:"providers" toml:"providers"`
+}
+
+type OIDCProviderConfig struct {
+	Name         string   `yaml:"name" toml:"name"`
+	Issuer       string   `yaml:"issuer" toml:"issuer"`
+	ClientID     string   `yaml:"client_id" toml:"client_id"`
+	ClientSecret string   `yaml:"client_secret" toml:"client_secret" secret:"true"`
+	Scopes       []string `yaml:"scopes" toml:"scopes"`
+}
+
+type WebAuthnConfig struct {
+	RPID   string `yaml:"rp_id" toml:"rp_id" env:"WEBAUTHN_RP_ID"`
+	RPName string `yaml:"rp_name" toml:"rp_name" env:"WEBAUTHN_RP_NAME"`
+	// RPOrigins defaults to the application base URL.
+	RPOrigins []string `yaml:"rp_origins" toml:"rp_origins" env:"WEBAUTHN_RP_ORIGINS"`
+}
+
+// Default returns the configuration used when nothing else is set.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:            3000,
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    15 * time.Second,
+			IdleTimeout:     time.Minute,
+			ShutdownTimeout: 30 * time.Second,
+		},
+		App: AppConfig{BaseURL: "http://localhost:3000"},
+		Database: DatabaseConfig{
+			Host:    "localhost",
+			Port:    5432,
+			SSLMode: "require",
+			Pool: PoolConfig{
+				MaxOpenConns:    20,
+				MaxIdleConns:    10,
+				ConnMaxLifetime: 30 * time.Minute,
+				ConnMaxIdleTime: 5 * time.Minute,
+			},
+			ReplicaPool: PoolConfig{
+				MaxOpenConns:    20,
+				MaxIdleConns:    10,
+				ConnMaxLifetime: 30 * time.Minute,
+				ConnMaxIdleTime: 5 * time.Minute,
+			},
+			ReadYourWritesWindow: 5 * time.Second,
+			ConnectTimeout:       time.Minute,
+			MaxRetries:           2,
+			RetryBackoff:         100 * time.Millisecond,
+			BreakerThreshold:     5,
+			BreakerCooldown:      10 * time.Second,
+			HealthCheckInterval:  10 * time.Second,
+		},
+		Log: LogConfig{
+			Level:          "info",
+			Sinks:          []string{logs.SinkStdout, logs.SinkFile},
+			File:           "logs/ticket-booking.log",
+			MaxSize:        100,
+			RotateInterval: 24 * time.Hour,
+			MaxAge:         14 * 24 * time.Hour,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			File:        "logs/traces.json",
+			SampleRatio: 1,
+			ServiceName: "ticket-booking",
+		},
+		JWT: JWTConfig{
+			Issuer:             "ticket-booking",
+			Audience:           "ticket-booking",
+			Expiry:             24 * time.Hour,
+			RefreshExpiry:      7 * 24 * time.Hour,
+			RevocationCacheTTL: 30 * time.Second,
+		},
+		Argon2: Argon2Config{
+			Memory:      64 * 1024,
+			Iterations:  3,
+			Parallelism: 2,
+		},
+		SignIn: SignInConfig{
+			MaxFailures:   5,
+			IPMaxFailures: 20,
+			IPWindow:      15 * time.Minute,
+			Lockout:       time.Minute,
+			MaxLockout:    time.Hour,
+		},
+		TOTP: TOTPConfig{Issuer: "Ticket-Booking"},
+		Mailer: MailerConfig{
+			Driver:    "outbox",
+			From:      "no-reply@ticket-booking.local",
+			OutboxDir: "outbox",
+			SMTP:      SMTPConfig{Port: 587},
+		},
+		OIDC: OIDCConfig{RedirectURL: "http://localhost:3000/api/auth/oidc/{provider}/callback"},
+		WebAuthn: WebAuthnConfig{
+			RPID:   "localhost",
+			RPName: "Ticket Booking",
+		},
+	}
+}
+
+// Load resolves the configuration from the command line arguments (without the program name), the
+// environment and the file given by --config or CONFIG_FILE, then validates it.
+func Load(args []string) (*Config, error) {
+	config := Default()
+
+	flags := flag.NewFlagSet("ticket-booking", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "path of a YAML or TOML configuration file")
+	flags.BoolVar(&config.PrintConfig, "print-config", false, "print the effective configuration, secrets redacted, and exit")
+
+	fields := envFields(reflect.ValueOf(config).Elem(), "")
+	values := make(map[string]*string, len(fields))
+	for _, field := range fields {
+		if field.value.Kind() == reflect.Bool {
+			// Boolean flags may be given without a value, e.g. --db-auto-migrate
+			value := new(string)
+			flags.BoolFunc(flagName(field.env), "overrides "+field.env, func(s string) error {
+				*value = s
+				return nil
+			})
+			values[field.env] = value
+			continue
+		}
+		values[field.env] = flags.String(flagName(field.env), "", "overrides "+field.env)
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		if err := config.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := config.loadEnv(fields); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	flags.Visit(func(f *flag.Flag) {
+		for _, field := range fields {
+			if flagName(field.env) == f.Name {
+				if err := setField(field.value, *values[field.env]); err != nil {
+					errs = append(errs, fmt.Errorf("--%s: %w", f.Name, err))
+				}
+			}
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	config.Args = flags.Args()
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, c)
+	case ".toml":
+		_, err = toml.Decode(string(content), c)
+	default:
+		return fmt.Errorf("config file %s: unsupported format, use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (c *Config) loadEnv(fields []envField) error {
+	var errs []error
+	for _, field := range fields {
+		if value, ok := os.LookupEnv(field.env); ok {
+			if err := setField(field.value, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", field.env, err))
+			}
+		}
+	}
+
+	if names, ok := os.LookupEnv("OIDC_PROVIDERS"); ok {
+		c.OIDC.Providers = nil
+		for _, name := range strings.Split(names, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+
+			prefix := "OIDC_" + strings.ToUpper(name) + "_"
+			c.OIDC.Providers = append(c.OIDC.Providers, OIDCProviderConfig{
+				Name:         name,
+				Issuer:       os.Getenv(prefix + "ISSUER"),
+				ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+				ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+				Scopes:       splitList(os.Getenv(prefix + "SCOPES")),
+			})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid setting at once, so a misconfigured deployment fails at startup.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port: %d is not a valid port", c.Server.Port)
+	check(c.Server.ReadTimeout > 0, "server.read_timeout must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(isAbsoluteURL(c.App.BaseURL), "app.base_url: %q is not an absolute URL", c.App.BaseURL)
+
+	check(c.Database.Host != "", "database.host is required")
+	check(c.Database.Port > 0 && c.Database.Port <= 65535, "database.port: %d is not a valid port", c.Database.Port)
+	check(c.Database.User != "", "database.user is required")
+	check(c.Database.Name != "", "database.name is required")
+	check(oneOf(c.Database.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full"),
+		"database.sslmode: %q is not a valid SSL mode", c.Database.SSLMode)
+	for _, pool := range []struct {
+		name string
+		PoolConfig
+	}{{"pool", c.Database.Pool}, {"replica_pool", c.Database.ReplicaPool}} {
+		name := pool.name
+		check(pool.MaxOpenConns >= 0, "database.%s.max_open_conns must not be negative", name)
+		check(pool.MaxIdleConns >= 0, "database.%s.max_idle_conns must not be negative", name)
+		check(pool.ConnMaxLifetime >= 0, "database.%s.conn_max_lifetime must not be negative", name)
+		check(pool.ConnMaxIdleTime >= 0, "database.%s.conn_max_idle_time must not be negative", name)
+	}
+	for i, replica := range c.Database.Replicas {
+		parsed, err := url.Parse(replica)
+		// The URL itself is not quoted, it holds the password
+		check(err == nil && (parsed.Scheme == "postgres" || parsed.Scheme == "postgresql") && parsed.Host != "",
+			"database.replicas[%d] is not a postgres:// URL", i)
+	}
+	check(c.Database.ReadYourWritesWindow >= 0, "database.read_your_writes_window must not be negative")
+	check(c.Database.ConnectTimeout > 0, "database.connect_timeout must be positive")
+	check(c.Database.MaxRetries >= 0, "database.max_retries must not be negative")
+	check(c.Database.RetryBackoff > 0, "database.retry_backoff must be positive")
+	check(c.Database.BreakerThreshold > 0, "database.breaker_threshold must be positive")
+	check(c.Database.BreakerCooldown > 0, "database.breaker_cooldown must be positive")
+	check(c.Database.HealthCheckInterval > 0, "database.health_check_interval must be positive")
+
+	check(oneOf(strings.ToLower(c.Log.Level), "debug", "info", "warn", "error", "fatal"),
+		"log.level: %q must be one of debug, info, warn, error or fatal", c.Log.Level)
+	check(len(c.Log.Sinks) > 0, "log.sinks: at least one sink is required")
+	for i, sink := range c.Log.Sinks {
+		check(oneOf(strings.ToLower(sink), logs.SinkStdout, logs.SinkConsole, logs.SinkFile),
+			"log.sinks[%d]: %q must be stdout, console or file", i, sink)
+		if strings.EqualFold(sink, logs.SinkFile) {
+			check(c.Log.File != "", "log.file is required with the file sink")
+		}
+	}
+	check(c.Log.MaxSize > 0, "log.max_size must be positive")
+	check(c.Log.RotateInterval >= 0, "log.rotate_interval must not be negative")
+	check(c.Log.MaxAge >= 0, "log.max_age must not be negative")
+	check(c.Log.MaxBackups >= 0, "log.max_backups must not be negative")
+
+	check(oneOf(strings.ToLower(c.Tracing.Exporter), "none", "stdout", "file", "otlp"),
+		"tracing.exporter: %q must be none, stdout, file or otlp", c.Tracing.Exporter)
+	if strings.EqualFold(c.Tracing.Exporter, "file") {
+		check(c.Tracing.File != "", "tracing.file is required with the file exporter")
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1")
+	check(c.Tracing.ServiceName != "", "tracing.service_name is required")
+
+	check(c.JWT.Issuer != "", "jwt.issuer is required")
+	check(c.JWT.Audience != "", "jwt.audience is required")
+	check(c.JWT.Expiry > 0, "jwt.expiry must be positive")
+	check(c.JWT.RefreshExpiry > c.JWT.Expiry, "jwt.refresh_expiry must be longer than jwt.expiry")
+	check(c.JWT.RevocationCacheTTL > 0, "jwt.revocation_cache_ttl must be positive")
+
+	check(c.Argon2.Memory >= 8*1024, "argon2.memory must be at least 8192 KiB")
+	check(c.Argon2.Iterations > 0, "argon2.iterations must be positive")
+	check(c.Argon2.Parallelism > 0, "argon2.parallelism must be positive")
+
+	check(c.SignIn.MaxFailures > 0, "sign_in.max_failures must be positive")
+	check(c.SignIn.IPMaxFailures > 0, "sign_in.ip_max_failures must be positive")
+	check(c.SignIn.IPWindow > 0, "sign_in.ip_window must be positive")
+	check(c.SignIn.Lockout > 0, "sign_in.lockout must be positive")
+	check(c.SignIn.MaxLockout >= c.SignIn.Lockout, "sign_in.max_lockout must not be shorter than sign_in.lockout")
+
+	check(c.TOTP.Issuer != "", "totp.issuer is required")
+
+	check(oneOf(strings.ToLower(c.Mailer.Driver), "smtp", "outbox"), "mailer.driver: %q must be smtp or outbox", c.Mailer.Driver)
+	check(c.Mailer.From != "", "mailer.from is required")
+	if strings.EqualFold(c.Mailer.Driver, "smtp") {
+		check(c.Mailer.SMTP.Host != "", "mailer.smtp.host is required with the smtp driver")
+		check(c.Mailer.SMTP.Port > 0 && c.Mailer.SMTP.Port <= 65535, "mailer.smtp.port: %d is not a valid port", c.Mailer.SMTP.Port)
+	} else {
+		check(c.Mailer.OutboxDir != "", "mailer.outbox_dir is required with the outbox driver")
+	}
+
+	check(strings.Contains(c.OIDC.RedirectURL, "{provider}"), "oidc.redirect_url must contain the {provider} placeholder")
+	names := make(map[string]bool)
+	for i, provider := range c.OIDC.Providers {
+		check(provider.Name != "", "oidc.providers[%d].name is required", i)
+		check(!names[strings.ToLower(provider.Name)], "oidc.providers[%d]: duplicate provider %q", i, provider.Name)
+		check(isAbsoluteURL(provider.Issuer), "oidc.providers[%d].issuer: %q is not an absolute URL", i, provider.Issuer)
+		check(provider.ClientID != "", "oidc.providers[%d].client_id is required", i)
+		names[strings.ToLower(provider.Name)] = true
+	}
+
+	check(c.WebAuthn.RPID != "", "webauthn.rp_id is required")
+	check(c.WebAuthn.RPName != "", "webauthn.rp_name is required")
+	for i, origin := range c.WebAuthn.RPOrigins {
+		check(isAbsoluteURL(origin), "webauthn.rp_origins[%d]: %q is not an absolute URL", i, origin)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Options returns the logger options matching the configuration.
+func (l LogConfig) Options() logs.Options {
+	return logs.Options{
+		Sinks:      l.Sinks,
+		File:       l.File,
+		MaxSize:    l.MaxSize,
+		MaxAge:     l.MaxAge,
+		MaxBackups: l.MaxBackups,
+		Compress:   l.Compress,
+	}
+}
+
+// ConnectionString returns the PostgreSQL URL of the database.
+func (d DatabaseConfig) ConnectionString() string {
+	connection := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(d.User, d.Password),
+		Host:     fmt.Sprintf("%s:%d", d.Host, d.Port),
+		Path:     "/" + d.Name,
+		RawQuery: url.Values{"sslmode": {d.SSLMode}}.Encode(),
+	}
+
+	return connection.String()
+}
+
+// Print writes the configuration as YAML, with the value of every secret field replaced.
+func (c *Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(redactedNode(reflect.ValueOf(c).Elem(), false)); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+const redacted = "[REDACTED]"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// redactedNode converts a configuration value into a YAML node, keeping the field order and writing
+// durations in their readable form.
+func redactedNode(value reflect.Value, secret bool) *yaml.Node {
+	switch {
+	case value.Type() == durationType:
+		return scalarNode(value.Interface().(time.Duration).String())
+	case value.Kind() == reflect.Struct:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+			if name == "-" || !field.IsExported() {
+				continue
+			}
+			node.Content = append(node.Content, scalarNode(name), redactedNode(value.Field(i), field.Tag.Get("secret") == "true"))
+		}
+		return node
+	case value.Kind() == reflect.Slice:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for i := 0; i < value.Len(); i++ {
+			item := redactedNode(value.Index(i), secret)
+			if item.Kind == yaml.MappingNode {
+				node.Style = 0
+			}
+			node.Content = append(node.Content, item)
+		}
+		return node
+	case secret && !value.IsZero():
+		return scalarNode(redacted)
+	default:
+		node := &yaml.Node{}
+		if err := node.Encode(value.Interface()); err != nil {
+			return scalarNode(fmt.Sprint(value.Interface()))
+		}
+		return node
+	}
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+type envField struct {
+	env   string
+	value reflect.Value
+}
+
+// envFields lists the settable fields carrying an env tag, depth first. A struct field may set envPrefix
+// to reuse the same struct type under different variable names.
+func envFields(value reflect.Value, prefix string) []envField {
+	var fields []envField
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if env := field.Tag.Get("env"); env != "" {
+			fields = append(fields, envField{env: prefix + env, value: value.Field(i)})
+		} else if field.Type.Kind() == reflect.Struct && field.Type != durationType {
+			fields = append(fields, envFields(value.Field(i), prefix+field.Tag.Get("envPrefix"))...)
+		}
+	}
+
+	return fields
+}
+
+// setField parses a string from the environment or the command line into the field.
+func setField(field reflect.Value, value string) error {
+	value = strings.TrimSpace(value)
+
+	if field.Type() == durationType {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(parsed))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int64:
+		parsed, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(parsed)
+	case reflect.Uint8, reflect.Uint32:
+		parsed, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(parsed)
+	case reflect.Float64:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(parsed)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(parsed)
+	case reflect.Slice:
+		field.Set(reflect.ValueOf(splitList(value)))
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+
+	return nil
+}
+
+// flagName turns an environment variable name into a flag name: DB_HOST becomes db-host.
+func flagName(env string) string {
+	return strings.ReplaceAll(strings.ToLower(env), "_", "-")
+}
+
+// splitList splits a comma or space separated list.
+func splitList(value string) []string {
+	return strings.Fields(strings.ReplaceAll(value, ",", " "))
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, candidate := range allowed {
+		if value == candidate {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isAbsoluteURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && parsed.Scheme != "" && parsed.Host != ""
+}
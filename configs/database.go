@@ -0,0 +1,303 @@
+package configs
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"ticket-booking/configs/logs"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// ErrDatabaseUnavailable is returned without querying while the circuit breaker is open.
+var ErrDatabaseUnavailable = errors.New("database unavailable")
+
+// Database is a connection pool guarded by a circuit breaker. Statements are retried on transient
+// errors only when that is safe: reads always, writes only when PostgreSQL reports it did not run them.
+type Database struct {
+	*sqlx.DB
+	name    string
+	breaker *breaker
+	retries int
+	backoff time.Duration
+}
+
+// connect opens a pool, retrying with exponential backoff until the connect timeout, so the
+// application can start before PostgreSQL accepts connections.
+func connect(name, dsn string, config DatabaseConfig, pool PoolConfig) (*Database, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.ConnectTimeout)
+	defer cancel()
+
+	delay := config.RetryBackoff
+	for {
+		db, err := sqlx.ConnectContext(ctx, "postgres", dsn)
+		if err == nil {
+			configurePool(db, pool)
+			return &Database{
+				DB:      db,
+				name:    name,
+				breaker: newBreaker(config.BreakerThreshold, config.BreakerCooldown),
+				retries: config.MaxRetries,
+				backoff: config.RetryBackoff,
+			}, nil
+		}
+
+		logs.Warn("Database not reachable yet, retrying", zap.String("database", name), zap.Duration("delay", delay), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("connecting to the %s database: %w", name, err)
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, 10*time.Second)
+	}
+}
+
+// Name identifies the pool in logs and health checks.
+func (d *Database) Name() string {
+	return d.name
+}
+
+// Available reports whether the circuit breaker lets statements through.
+func (d *Database) Available() bool {
+	return d.breaker.closed()
+}
+
+// RetryAfter is how long the circuit breaker stays open, or zero.
+func (d *Database) RetryAfter() time.Duration {
+	return d.breaker.retryAfter()
+}
+
+func (d *Database) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return d.do(ctx, query, isRetryableWrite, func(ctx context.Context) error {
+		return d.DB.GetContext(ctx, dest, query, args...)
+	})
+}
+
+func (d *Database) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return d.do(ctx, query, isRetryableWrite, func(ctx context.Context) error {
+		return d.DB.SelectContext(ctx, dest, query, args...)
+	})
+}
+
+func (d *Database) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := d.do(ctx, query, isRetryableWrite, func(ctx context.Context) error {
+		var err error
+		result, err = d.DB.ExecContext(ctx, query, args...)
+		return err
+	})
+
+	return result, err
+}
+
+// BeginTxx is guarded by the circuit breaker but never retried; the caller owns the transaction.
+func (d *Database) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error) {
+	var tx *sqlx.Tx
+	err := d.do(ctx, "BEGIN", func(error) bool { return false }, func(ctx context.Context) error {
+		var err error
+		tx, err = d.DB.BeginTxx(ctx, opts)
+		return err
+	})
+
+	return tx, err
+}
+
+// read runs a read-only statement, which can be retried on any transient error.
+func (d *Database) read(ctx context.Context, query string, fn func(ctx context.Context, db *sqlx.DB) error) error {
+	return d.do(ctx, query, isTransient, func(ctx context.Context) error {
+		return fn(ctx, d.DB)
+	})
+}
+
+// do runs a statement in its own span, retrying it while retryable allows.
+func (d *Database) do(ctx context.Context, query string, retryable func(error) bool, fn func(ctx context.Context) error) (err error) {
+	ctx, span := startStatementSpan(ctx, d.name, query)
+	defer func() { EndSpan(span, err) }()
+
+	delay := d.backoff
+	for attempt := 0; ; attempt++ {
+		if !d.breaker.allow() {
+			return ErrDatabaseUnavailable
+		}
+
+		err = fn(ctx)
+		d.breaker.record(err)
+		if err == nil || attempt >= d.retries || !retryable(err) {
+			return err
+		}
+
+		span.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", attempt+1), attribute.String("error", err.Error())))
+		logs.WarnContext(ctx, "Retrying database statement after a transient error", zap.String("database", d.name), zap.Int("attempt", attempt+1), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// RunHealthCheck pings the database periodically. Pings feed the circuit breaker, so it closes again
+// as soon as the database is back rather than on the next request.
+func (d *Database) RunHealthCheck(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, interval)
+			err := d.PingContext(pingCtx)
+			cancel()
+
+			wasAvailable := d.Available()
+			d.breaker.ping(err)
+			if err != nil {
+				logs.ErrorContext(ctx, "Database health check failed", err, zap.String("database", d.name))
+			} else if !wasAvailable {
+				logs.InfoContext(ctx, "Database reachable again", zap.String("database", d.name))
+			}
+		}
+	}
+}
+
+// isConnectionError reports whether err means the database could not be reached, as opposed to
+// the database answering with an error.
+func isConnectionError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code.Class() == "08", // connection_exception
+			pqErr.Code == "57P01", // admin_shutdown
+			pqErr.Code == "57P02", // crash_shutdown
+			pqErr.Code == "57P03": // cannot_connect_now
+			return true
+		}
+	}
+
+	return false
+}
+
+// isTransient reports whether a read failing with err may succeed if run again.
+func isTransient(err error) bool {
+	return isConnectionError(err) || isRetryableWrite(err)
+}
+
+// isRetryableWrite reports whether PostgreSQL guarantees the failed statement had no effect, so
+// running it again cannot apply it twice.
+func isRetryableWrite(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+
+	switch pqErr.Code {
+	case "40001", // serialization_failure
+		"40P01", // deadlock_detected
+		"57P03", // cannot_connect_now
+		"53300": // too_many_connections
+		return true
+	}
+
+	return false
+}
+
+// breaker opens after threshold consecutive connection errors. Once the cooldown has elapsed it lets
+// a single probe through: success closes it, failure keeps it open for another cooldown.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	probing   bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{threshold: threshold, cooldown: cooldown}
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openedAt.IsZero() {
+		return true
+	}
+
+	if b.probing || time.Since(b.openedAt) < b.cooldown {
+		return false
+	}
+
+	b.probing = true
+	return true
+}
+
+// record updates the breaker with the outcome of a statement it allowed.
+func (b *breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	switch {
+	case err == nil || (!isConnectionError(err) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)):
+		// The database answered
+		b.failures = 0
+		b.openedAt = time.Time{}
+	case isConnectionError(err):
+		b.failures++
+		if b.failures >= b.threshold || !b.openedAt.IsZero() {
+			b.openedAt = time.Now()
+		}
+	}
+}
+
+// ping updates the breaker with the outcome of a health check, which needs no permission to run.
+func (b *breaker) ping(err error) {
+	if err != nil && !isConnectionError(err) {
+		// A ping can only fail to reach the database; treat anything else, e.g. a timeout, the same way
+		err = fmt.Errorf("%w: %v", driver.ErrBadConn, err)
+	}
+
+	b.record(err)
+}
+
+func (b *breaker) closed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.openedAt.IsZero()
+}
+
+func (b *breaker) retryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openedAt.IsZero() {
+		return 0
+	}
+
+	return max(b.cooldown-time.Since(b.openedAt), 0)
+}
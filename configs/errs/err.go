@@ -3,6 +3,8 @@ package errs
 import (
 	"net/http"
 
+	"ticket-booking/configs/logs"
+
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -10,6 +12,8 @@ type Error struct {
 	Status  int    `json:"status"`
 	ErrType string `json:"error"`
 	Message string `json:"message"`
+	// RequestID lets a client report an error that support can find in the logs.
+	RequestID string `json:"request_id,omitempty"`
 }
 
 func NewError(message, errType string, status int) *Error {
@@ -21,21 +25,36 @@ func NewError(message, errType string, status int) *Error {
 }
 
 func NewBadRequest(ctx *fiber.Ctx, message string) error {
-	err := NewError(message, "bad_request_error", http.StatusBadRequest)
-	return ctx.Status(http.StatusBadRequest).JSON(err)
+	return respond(ctx, message, "bad_request_error", http.StatusBadRequest)
 }
 
 func NewNotFound(ctx *fiber.Ctx, message string) error {
-	err := NewError(message, "not_found_error", http.StatusNotFound)
-	return ctx.Status(http.StatusNotFound).JSON(err)
+	return respond(ctx, message, "not_found_error", http.StatusNotFound)
 }
 
 func NewInternalServerError(ctx *fiber.Ctx, message string) error {
-	err := NewError(message, "internal_server_error", http.StatusInternalServerError)
-	return ctx.Status(http.StatusInternalServerError).JSON(err)
+	return respond(ctx, message, "internal_server_error", http.StatusInternalServerError)
 }
 
 func NewUnauthorized(ctx *fiber.Ctx, message string) error {
-	err := NewError(message, "unauthorized_error", http.StatusUnauthorized)
-	return ctx.Status(http.StatusUnauthorized).JSON(err)
+	return respond(ctx, message, "unauthorized_error", http.StatusUnauthorized)
+}
+
+func NewForbidden(ctx *fiber.Ctx, message string) error {
+	return respond(ctx, message, "forbidden_error", http.StatusForbidden)
+}
+
+func NewTooManyRequests(ctx *fiber.Ctx, message string) error {
+	return respond(ctx, message, "too_many_requests_error", http.StatusTooManyRequests)
+}
+
+func NewServiceUnavailable(ctx *fiber.Ctx, message string) error {
+	return respond(ctx, message, "service_unavailable_error", http.StatusServiceUnavailable)
+}
+
+// respond writes the error, tagged with the ID of the request it answers.
+func respond(ctx *fiber.Ctx, message, errType string, status int) error {
+	err := NewError(message, errType, status)
+	err.RequestID = logs.RequestID(ctx.UserContext())
+	return ctx.Status(status).JSON(err)
 }
@@ -1,53 +1,126 @@
 package logs
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Sinks a log entry can be written to.
+const (
+	// SinkStdout writes JSON to stdout, for log collectors.
+	SinkStdout = "stdout"
+	// SinkConsole writes human readable lines to stdout, for development.
+	SinkConsole = "console"
+	// SinkFile writes JSON to a rotated file.
+	SinkFile = "file"
 )
 
 var (
 	log *zap.Logger
+	// level is shared by every sink so SetLogLevel takes effect immediately
+	level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	// file is the rotated log file, when the file sink is enabled
+	file *lumberjack.Logger
 )
 
+var encoderConfig = zapcore.EncoderConfig{
+	MessageKey:   "message",
+	LevelKey:     "level",
+	TimeKey:      "time",
+	EncodeTime:   zapcore.ISO8601TimeEncoder,
+	EncodeLevel:  zapcore.LowercaseLevelEncoder,
+	EncodeCaller: zapcore.ShortCallerEncoder,
+}
+
+// Options selects the sinks and how the log file is rotated.
+type Options struct {
+	Sinks []string
+	File  string
+	// MaxSize is the size in megabytes at which the file is rotated.
+	MaxSize int
+	// Rotated files older than MaxAge, rounded up to whole days, or beyond the MaxBackups newest are
+	// deleted; zero keeps them.
+	MaxAge     time.Duration
+	MaxBackups int
+	Compress   bool
+}
+
 func init() {
-	// Cria a pasta de logs se não existir
-	createLogDir()
-
-	// Configura o logger para registrar requisições e erros em um único arquivo
-	logConfig := zap.Config{
-		Level:       zap.NewAtomicLevelAt(getLogLevel()),
-		Encoding:    "json",
-		OutputPaths: []string{getCombinedLogFile()},
-		EncoderConfig: zapcore.EncoderConfig{
-			MessageKey:   "message",
-			LevelKey:     "level",
-			TimeKey:      "time",
-			EncodeTime:   zapcore.ISO8601TimeEncoder,
-			EncodeLevel:  zapcore.LowercaseLevelEncoder,
-			EncodeCaller: zapcore.ShortCallerEncoder,
-		},
+	// Until Configure is called, e.g. while the configuration loads, log JSON to stdout
+	log = zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.Lock(os.Stdout), level))
+}
+
+// Configure replaces the sinks. It is meant to be called once at startup, before logging from other goroutines.
+func Configure(options Options) error {
+	var (
+		cores   []zapcore.Core
+		logFile *lumberjack.Logger
+	)
+	for _, sink := range options.Sinks {
+		switch strings.ToLower(sink) {
+		case SinkStdout:
+			cores = append(cores, zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.Lock(os.Stdout), level))
+		case SinkConsole:
+			console := encoderConfig
+			console.EncodeLevel = zapcore.CapitalLevelEncoder
+			cores = append(cores, zapcore.NewCore(zapcore.NewConsoleEncoder(console), zapcore.Lock(os.Stdout), level))
+		case SinkFile:
+			if err := os.MkdirAll(filepath.Dir(options.File), os.ModePerm); err != nil {
+				return fmt.Errorf("creating the log directory: %w", err)
+			}
+			logFile = &lumberjack.Logger{
+				Filename:   options.File,
+				MaxSize:    options.MaxSize,
+				MaxAge:     int(math.Ceil(options.MaxAge.Hours() / 24)),
+				MaxBackups: options.MaxBackups,
+				Compress:   options.Compress,
+				LocalTime:  true,
+			}
+			cores = append(cores, zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.AddSync(logFile), level))
+		default:
+			return fmt.Errorf("unknown log sink %q", sink)
+		}
 	}
 
-	var err error
-	log, err = logConfig.Build()
-	if err != nil {
-		panic(err)
+	previous := file
+	log = zap.New(zapcore.NewTee(cores...))
+	file = logFile
+	if previous != nil {
+		_ = previous.Close()
 	}
-}
 
-func createLogDir() {
-	// Define o caminho do diretório de logs
-	logDir := "logs"
+	return nil
+}
 
-	// Tenta criar o diretório de logs, se não existir
-	if err := os.MkdirAll(logDir, os.ModePerm); err != nil {
-		panic("failed to create logs directory: " + err.Error())
+// RunRotation rotates the log file at every multiple of interval, e.g. at midnight UTC for 24h, on top
+// of the size-based rotation. Rotating also deletes the files past retention.
+func RunRotation(ctx context.Context, interval time.Duration) {
+	for {
+		now := time.Now()
+		timer := time.NewTimer(now.Truncate(interval).Add(interval).Sub(now))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			if file == nil {
+				continue
+			}
+			if err := file.Rotate(); err != nil {
+				Error("Error rotating the log file", err)
+			}
+		}
 	}
 }
 
@@ -55,17 +128,14 @@ func Sync() {
 	_ = log.Sync()
 }
 
-func Request(method, path string, status int, duration string) {
-	log.Info("HTTP Request",
+func Request(method, path string, status int, duration string, tags ...zap.Field) {
+	tags = append([]zap.Field{
 		zap.String("method", method),
 		zap.String("path", path),
 		zap.Int("status", status),
 		zap.String("duration", duration),
-	)
-	logMessage := fmt.Sprintf("HTTP Request - Method: %s, Path: %s, Status: %d, Duration: %s",
-		method, path, status, duration)
-
-	fmt.Println(logMessage)
+	}, tags...)
+	log.Info("HTTP Request", tags...)
 }
 
 func Info(message string, tags ...zap.Field) {
@@ -95,47 +165,84 @@ func Fatal(message string, err error, tags ...zap.Field) {
 	_ = log.Sync()
 }
 
-func getCombinedLogFile() string {
-	// Gera o nome do arquivo de log baseado na data atual
-	date := time.Now().Format("20060102")     // Formato: YYYYMMDD
-	return filepath.Join("logs", date+".log") // Define o caminho do log com apenas YYYYMMDD.log
+// InfoContext, DebugContext, WarnContext and ErrorContext add the request and trace IDs found in ctx
+// to the entry, so every entry of a request can be found together.
+func InfoContext(ctx context.Context, message string, tags ...zap.Field) {
+	Info(message, append(tags, ContextFields(ctx)...)...)
 }
 
-func getLogLevel() zapcore.Level {
-	level := strings.TrimSpace(os.Getenv("LOG_LEVEL"))
+func DebugContext(ctx context.Context, message string, tags ...zap.Field) {
+	Debug(message, append(tags, ContextFields(ctx)...)...)
+}
 
-	switch strings.ToLower(level) {
-	case "debug":
-		return zapcore.DebugLevel
-	case "warn":
-		return zapcore.WarnLevel
-	case "error":
-		return zapcore.ErrorLevel
-	case "fatal":
-		return zapcore.FatalLevel
-	default:
-		return zapcore.InfoLevel
+func WarnContext(ctx context.Context, message string, tags ...zap.Field) {
+	Warn(message, append(tags, ContextFields(ctx)...)...)
+}
+
+func ErrorContext(ctx context.Context, message string, err error, tags ...zap.Field) {
+	Error(message, err, append(tags, ContextFields(ctx)...)...)
+}
+
+type requestIDKey struct{}
+
+// WithRequestID attaches the ID of the request being served to ctx.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the ID of the request being served, or an empty string outside of a request.
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// ContextFields returns the request ID and the IDs of the span in ctx, so a log entry can be tied to
+// its request and trace.
+func ContextFields(ctx context.Context) []zap.Field {
+	var fields []zap.Field
+	if requestID := RequestID(ctx); requestID != "" {
+		fields = append(fields, zap.String("request_id", requestID))
+	}
+
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		fields = append(fields,
+			zap.String("trace_id", spanContext.TraceID().String()),
+			zap.String("span_id", spanContext.SpanID().String()),
+		)
 	}
+
+	return fields
 }
 
-// SetLogLevel dynamically changes the log level at runtime
-func SetLogLevel(level string) {
-	logLevel := getLogLevelFromString(level)
-	logConfig := zap.NewAtomicLevelAt(logLevel)
-	logConfig.SetLevel(logLevel)
+// LogLevel returns the name of the current log level.
+func LogLevel() string {
+	return level.Level().String()
+}
+
+// SetLogLevel changes the log level of every sink at runtime.
+func SetLogLevel(name string) error {
+	parsed, err := getLogLevelFromString(name)
+	if err != nil {
+		return err
+	}
+
+	level.SetLevel(parsed)
+	return nil
 }
 
-func getLogLevelFromString(level string) zapcore.Level {
-	switch strings.ToLower(level) {
+func getLogLevelFromString(level string) (zapcore.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
 	case "debug":
-		return zapcore.DebugLevel
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
 	case "warn":
-		return zapcore.WarnLevel
+		return zapcore.WarnLevel, nil
 	case "error":
-		return zapcore.ErrorLevel
+		return zapcore.ErrorLevel, nil
 	case "fatal":
-		return zapcore.FatalLevel
+		return zapcore.FatalLevel, nil
 	default:
-		return zapcore.InfoLevel
+		return zapcore.InfoLevel, fmt.Errorf("unknown log level %q", level)
 	}
 }
@@ -0,0 +1,159 @@
+package configs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ticket-booking/configs/logs"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// Server runs the HTTP application and the background workers, and shuts them down in order: first
+// the listener is closed and in-flight requests drain, then the workers stop, then the registered
+// resources (database pools) close, and finally the logs are flushed.
+type Server struct {
+	app          *fiber.App
+	timeout      time.Duration
+	workers      sync.WaitGroup
+	workersCtx   context.Context
+	stopWorkers  context.CancelFunc
+	closers      []closer
+	mu           sync.Mutex
+	running      map[string]bool
+	shuttingDown atomic.Bool
+	shutdownOnce sync.Once
+	shutdownErr  error
+	// syncLogs flushes the logs as the last step of shutdown.
+	syncLogs func()
+}
+
+type closer struct {
+	name  string
+	close func() error
+}
+
+// NewServer wraps the application; each shutdown phase may take up to timeout.
+func NewServer(app *fiber.App, timeout time.Duration) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Server{
+		app:         app,
+		timeout:     timeout,
+		workersCtx:  ctx,
+		stopWorkers: cancel,
+		running:     make(map[string]bool),
+		syncLogs:    logs.Sync,
+	}
+}
+
+// Go starts a background worker. Its context is cancelled once the requests have drained, and
+// shutdown waits for it to return.
+func (s *Server) Go(name string, worker func(ctx context.Context)) {
+	s.mu.Lock()
+	s.running[name] = true
+	s.mu.Unlock()
+
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		worker(s.workersCtx)
+
+		s.mu.Lock()
+		s.running[name] = false
+		s.mu.Unlock()
+		logs.Debug("Background worker stopped", zap.String("worker", name))
+	}()
+}
+
+// Workers reports, for every background worker, whether it is still running.
+func (s *Server) Workers() map[string]bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	workers := make(map[string]bool, len(s.running))
+	for name, running := range s.running {
+		workers[name] = running
+	}
+
+	return workers
+}
+
+// OnShutdown registers a resource to close after the workers stopped, in registration order.
+func (s *Server) OnShutdown(name string, close func() error) {
+	s.closers = append(s.closers, closer{name: name, close: close})
+}
+
+// ShuttingDown reports whether shutdown has started, so readiness checks can fail while draining.
+func (s *Server) ShuttingDown() bool {
+	return s.shuttingDown.Load()
+}
+
+// Run serves on address until ctx is cancelled, typically by SIGTERM or SIGINT, or the listener fails,
+// then shuts down. It returns the listener error, if any, joined with the shutdown errors.
+func (s *Server) Run(ctx context.Context, address string) error {
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- s.app.Listen(address)
+	}()
+
+	var err error
+	select {
+	case <-ctx.Done():
+		logs.Info("Shutdown signal received, draining requests", zap.Duration("timeout", s.timeout))
+	case err = <-listenErr:
+		if err != nil {
+			logs.Error("Error starting server", err)
+			err = fmt.Errorf("listening on %s: %w", address, err)
+		}
+	}
+
+	return errors.Join(err, s.Shutdown())
+}
+
+// Shutdown stops everything in order. It is safe to call more than once; later calls return the
+// result of the first.
+func (s *Server) Shutdown() error {
+	s.shutdownOnce.Do(func() {
+		s.shuttingDown.Store(true)
+
+		var errs []error
+		if err := s.app.ShutdownWithTimeout(s.timeout); err != nil {
+			logs.Error("Error draining requests", err)
+			errs = append(errs, fmt.Errorf("draining requests: %w", err))
+		}
+
+		s.stopWorkers()
+		stopped := make(chan struct{})
+		go func() {
+			s.workers.Wait()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(s.timeout):
+			err := errors.New("background workers did not stop in time")
+			logs.Error("Error stopping background workers", err)
+			errs = append(errs, err)
+		}
+
+		for _, closer := range s.closers {
+			if err := closer.close(); err != nil {
+				logs.Error("Error closing "+closer.name, err)
+				errs = append(errs, fmt.Errorf("closing %s: %w", closer.name, err))
+			}
+		}
+
+		logs.Info("Shutdown complete")
+		s.syncLogs()
+
+		s.shutdownErr = errors.Join(errs...)
+	})
+
+	return s.shutdownErr
+}
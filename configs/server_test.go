@@ -0,0 +1,200 @@
+package configs
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// events records what happened during shutdown, in order.
+type events struct {
+	mu   sync.Mutex
+	list []string
+}
+
+func (e *events) add(event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.list = append(e.list, event)
+}
+
+func (e *events) all() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return slices.Clone(e.list)
+}
+
+// freeAddress returns a local address nothing listens on.
+func freeAddress(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().String()
+}
+
+// waitFor polls condition until it holds or the deadline passes.
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func accepting(address string) bool {
+	conn, err := net.DialTimeout("tcp", address, 100*time.Millisecond)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+func TestServerShutdownOrder(t *testing.T) {
+	var recorded events
+	address := freeAddress(t)
+
+	inFlight := make(chan struct{})
+	release := make(chan struct{})
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/slow", func(c *fiber.Ctx) error {
+		close(inFlight)
+		<-release
+		recorded.add("request drained")
+		return c.SendString("done")
+	})
+
+	server := NewServer(app, 5*time.Second)
+	server.syncLogs = func() { recorded.add("logs synced") }
+
+	workerCancelled := make(chan struct{})
+	server.Go("worker", func(ctx context.Context) {
+		<-ctx.Done()
+		close(workerCancelled)
+		recorded.add("worker cancelled")
+		// Shutdown must wait for the worker to return, not only cancel it
+		time.Sleep(50 * time.Millisecond)
+		recorded.add("worker returned")
+	})
+	for _, name := range []string{"first", "second"} {
+		server.OnShutdown(name, func() error {
+			recorded.add("closed " + name)
+			return nil
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- server.Run(ctx, address) }()
+	waitFor(t, "the listener", func() bool { return accepting(address) })
+
+	response := make(chan *http.Response, 1)
+	go func() {
+		client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+		resp, err := client.Get("http://" + address + "/slow")
+		if err != nil {
+			t.Errorf("in-flight request failed: %v", err)
+		}
+		response <- resp
+	}()
+	<-inFlight
+
+	cancel()
+	waitFor(t, "the listener to close", func() bool { return !accepting(address) })
+	recorded.add("listener closed")
+
+	if !server.ShuttingDown() {
+		t.Error("ShuttingDown = false while draining")
+	}
+	select {
+	case <-workerCancelled:
+		t.Fatal("worker cancelled before the requests drained")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not return")
+	}
+
+	if resp := <-response; resp != nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("in-flight request status = %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+	}
+
+	want := []string{
+		"listener closed",
+		"request drained",
+		"worker cancelled",
+		"worker returned",
+		"closed first",
+		"closed second",
+		"logs synced",
+	}
+	if got := recorded.all(); !slices.Equal(got, want) {
+		t.Errorf("shutdown events = %q, want %q", got, want)
+	}
+
+	if running := server.Workers()["worker"]; running {
+		t.Error("worker still reported as running after shutdown")
+	}
+}
+
+func TestServerShutdownWorkerTimeout(t *testing.T) {
+	var recorded events
+
+	server := NewServer(fiber.New(fiber.Config{DisableStartupMessage: true}), 50*time.Millisecond)
+	server.syncLogs = func() { recorded.add("logs synced") }
+
+	stuck := make(chan struct{})
+	t.Cleanup(func() { close(stuck) })
+	server.Go("stuck worker", func(context.Context) { <-stuck })
+	server.OnShutdown("pool", func() error {
+		recorded.add("closed pool")
+		return errors.New("already closed")
+	})
+
+	err := server.Shutdown()
+	if err == nil || !strings.Contains(err.Error(), "background workers did not stop in time") {
+		t.Errorf("Shutdown error = %v, want the workers timeout", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "closing pool: already closed") {
+		t.Errorf("Shutdown error = %v, want the closer error", err)
+	}
+
+	// The resources are still released and the logs flushed
+	if got, want := recorded.all(), []string{"closed pool", "logs synced"}; !slices.Equal(got, want) {
+		t.Errorf("shutdown events = %q, want %q", got, want)
+	}
+
+	if again := server.Shutdown(); again != err {
+		t.Errorf("second Shutdown = %v, want the first result %v", again, err)
+	}
+}
@@ -1,35 +1,189 @@
 package configs
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"net/url"
-	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 )
 
-func GetReaderSqlx() *sqlx.DB {
-	connStr := buildConnectionString()
-	reader := sqlx.MustConnect("postgres", connStr)
+// Reader is the part of a connection pool the repositories read through.
+type Reader interface {
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// Replicas spreads reads over the read replicas in turn. Reads of an account that wrote within the
+// read-your-writes window go to the primary instead, as do all reads when there is no replica.
+type Replicas struct {
+	primary   *Database
+	pools     []*Database
+	next      atomic.Uint64
+	window    time.Duration
+	mu        sync.Mutex
+	writes    map[uuid.UUID]time.Time
+	lastPrune time.Time
+}
+
+type (
+	accountKey      struct{}
+	primaryReadsKey struct{}
+)
+
+// WithAccount marks the context as acting on behalf of the account, for read-your-writes routing.
+func WithAccount(ctx context.Context, accountID uuid.UUID) context.Context {
+	return context.WithValue(ctx, accountKey{}, accountID)
+}
+
+// WithPrimaryReads sends every read made with the context to the primary, for requests that write
+// and read back what they wrote.
+func WithPrimaryReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryReadsKey{}, true)
+}
+
+func accountFromContext(ctx context.Context) (uuid.UUID, bool) {
+	accountID, ok := ctx.Value(accountKey{}).(uuid.UUID)
+	return accountID, ok
+}
+
+func GetReaderSqlx(config DatabaseConfig, writer *Database) (*Replicas, error) {
+	replicas := &Replicas{
+		primary: writer,
+		window:  config.ReadYourWritesWindow,
+		writes:  make(map[uuid.UUID]time.Time),
+	}
+
+	for i, url := range config.Replicas {
+		reader, err := connect(fmt.Sprintf("replica %d", i+1), url, config, config.ReplicaPool)
+		if err != nil {
+			_ = replicas.Close()
+			return nil, err
+		}
+		replicas.pools = append(replicas.pools, reader)
+	}
+
+	return replicas, nil
+}
 
-	return reader
+func GetWriterSqlx(config DatabaseConfig) (*Database, error) {
+	return connect("primary", config.ConnectionString(), config, config.Pool)
 }
 
-func GetWriterSqlx() *sqlx.DB {
-	connStr := buildConnectionString()
-	writer := sqlx.MustConnect("postgres", connStr)
+func configurePool(db *sqlx.DB, config PoolConfig) {
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+}
+
+func (r *Replicas) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return r.pick(ctx).read(ctx, query, func(ctx context.Context, db *sqlx.DB) error {
+		return db.GetContext(ctx, dest, query, args...)
+	})
+}
 
-	return writer
+func (r *Replicas) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return r.pick(ctx).read(ctx, query, func(ctx context.Context, db *sqlx.DB) error {
+		return db.SelectContext(ctx, dest, query, args...)
+	})
 }
 
-func buildConnectionString() string {
-	user := os.Getenv("DB_USER")
-	password := url.QueryEscape(os.Getenv("DB_PASSWORD"))
-	host := os.Getenv("DB_HOST")
-	port := os.Getenv("DB_PORT")
-	dbname := os.Getenv("DB_NAME")
-	sslmode := os.Getenv("DB_SSLMODE")
+// Window returns how long reads go to the primary after a write, or zero when there is no replica to lag behind.
+func (r *Replicas) Window() time.Duration {
+	if len(r.pools) == 0 {
+		return 0
+	}
+
+	return max(r.window, 0)
+}
+
+// RecordWrite starts the read-your-writes window of the account on this instance. Other instances
+// only learn of the write through the marker the client sends back, see middlewares.ReadYourWrites.
+func (r *Replicas) RecordWrite(accountID uuid.UUID) {
+	if r.Window() == 0 {
+		return
+	}
+
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.writes[accountID] = now
+	if now.Sub(r.lastPrune) > r.window {
+		for id, wroteAt := range r.writes {
+			if now.Sub(wroteAt) > r.window {
+				delete(r.writes, id)
+			}
+		}
+		r.lastPrune = now
+	}
+}
+
+// Close closes the replica pools; the primary is closed by its owner.
+func (r *Replicas) Close() error {
+	var errs []error
+	for _, pool := range r.pools {
+		errs = append(errs, pool.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// Pools returns the replica pools, without the primary.
+func (r *Replicas) Pools() []*Database {
+	return r.pools
+}
+
+// RunHealthCheck pings every replica periodically; the primary is checked by its own health check.
+func (r *Replicas) RunHealthCheck(ctx context.Context, interval time.Duration) {
+	var wg sync.WaitGroup
+	for _, pool := range r.pools {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pool.RunHealthCheck(ctx, interval)
+		}()
+	}
+	wg.Wait()
+}
+
+// pick returns the pool to read from. Replicas whose circuit breaker is open are skipped, falling
+// back to the primary when none is available.
+func (r *Replicas) pick(ctx context.Context) *Database {
+	if primary, _ := ctx.Value(primaryReadsKey{}).(bool); primary || len(r.pools) == 0 || r.wroteRecently(ctx) {
+		return r.primary
+	}
+
+	start := r.next.Add(1) - 1
+	for i := range uint64(len(r.pools)) {
+		if pool := r.pools[(start+i)%uint64(len(r.pools))]; pool.Available() {
+			return pool
+		}
+	}
+
+	return r.primary
+}
+
+func (r *Replicas) wroteRecently(ctx context.Context) bool {
+	if r.window <= 0 {
+		return false
+	}
+
+	accountID, ok := accountFromContext(ctx)
+	if !ok {
+		return false
+	}
+
+	r.mu.Lock()
+	wroteAt, ok := r.writes[accountID]
+	r.mu.Unlock()
 
-	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s", user, password, host, port, dbname, sslmode)
+	return ok && time.Since(wroteAt) <= r.window
 }
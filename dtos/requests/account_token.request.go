@@ -0,0 +1,73 @@
+package requests
+
+import "github.com/go-playground/validator/v10"
+
+// TokenRequest represents a request consuming a token received by email.
+type TokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// NewTokenRequest creates a new instance of TokenRequest.
+func NewTokenRequest(token string) *TokenRequest {
+	return &TokenRequest{
+		Token: token,
+	}
+}
+
+// Validate validates the TokenRequest fields.
+func (t *TokenRequest) Validate() error {
+	return validator.New().Struct(t)
+}
+
+// ForgotPasswordRequest represents a request for a password reset email.
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// NewForgotPasswordRequest creates a new instance of ForgotPasswordRequest.
+func NewForgotPasswordRequest(email string) *ForgotPasswordRequest {
+	return &ForgotPasswordRequest{
+		Email: email,
+	}
+}
+
+// Validate validates the ForgotPasswordRequest fields.
+func (f *ForgotPasswordRequest) Validate() error {
+	return validator.New().Struct(f)
+}
+
+// ResetPasswordRequest represents a request setting a new password with a reset token.
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+// NewResetPasswordRequest creates a new instance of ResetPasswordRequest.
+func NewResetPasswordRequest(token, password string) *ResetPasswordRequest {
+	return &ResetPasswordRequest{
+		Token:    token,
+		Password: password,
+	}
+}
+
+// Validate validates the ResetPasswordRequest fields.
+func (r *ResetPasswordRequest) Validate() error {
+	return validator.New().Struct(r)
+}
+
+// MagicLinkRequest represents a request for a sign-in link sent by email.
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// NewMagicLinkRequest creates a new instance of MagicLinkRequest.
+func NewMagicLinkRequest(email string) *MagicLinkRequest {
+	return &MagicLinkRequest{
+		Email: email,
+	}
+}
+
+// Validate validates the MagicLinkRequest fields.
+func (m *MagicLinkRequest) Validate() error {
+	return validator.New().Struct(m)
+}
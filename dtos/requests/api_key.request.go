@@ -0,0 +1,45 @@
+package requests
+
+import (
+	"fmt"
+	"ticket-booking/entities"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// APIKeyRequest represents a request to create an API key.
+type APIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// NewAPIKeyRequest creates a new instance of APIKeyRequest.
+func NewAPIKeyRequest(name string, scopes []string, expiresAt *time.Time) *APIKeyRequest {
+	return &APIKeyRequest{
+		Name:      name,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+}
+
+// Validate validates the APIKeyRequest fields, ensuring every scope may be given to a key
+// and the expiry, if any, is in the future.
+func (a *APIKeyRequest) Validate() error {
+	if err := validator.New().Struct(a); err != nil {
+		return err
+	}
+
+	for _, scope := range a.Scopes {
+		if !entities.Permission(scope).IsAPIKeyScope() {
+			return fmt.Errorf("scope not allowed for API keys: %s", scope)
+		}
+	}
+
+	if a.ExpiresAt != nil && !a.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("expiry must be in the future")
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package requests
+
+import "github.com/go-playground/validator/v10"
+
+// LogLevelRequest represents a request to change the log level at runtime.
+type LogLevelRequest struct {
+	Level string `json:"level" validate:"required,oneof=debug info warn error fatal"`
+}
+
+// NewLogLevelRequest creates a new instance of LogLevelRequest.
+func NewLogLevelRequest(level string) *LogLevelRequest {
+	return &LogLevelRequest{
+		Level: level,
+	}
+}
+
+// Validate validates the LogLevelRequest fields.
+func (l *LogLevelRequest) Validate() error {
+	return validator.New().Struct(l)
+}
@@ -0,0 +1,23 @@
+package requests
+
+import "github.com/go-playground/validator/v10"
+
+// OIDCCallbackRequest represents the redirect back from an identity provider,
+// received either as query parameters or relayed by the frontend as a JSON body.
+type OIDCCallbackRequest struct {
+	Code  string `query:"code" json:"code" validate:"required"`
+	State string `query:"state" json:"state" validate:"required"`
+}
+
+// NewOIDCCallbackRequest creates a new instance of OIDCCallbackRequest.
+func NewOIDCCallbackRequest(code, state string) *OIDCCallbackRequest {
+	return &OIDCCallbackRequest{
+		Code:  code,
+		State: state,
+	}
+}
+
+// Validate validates the OIDCCallbackRequest fields.
+func (o *OIDCCallbackRequest) Validate() error {
+	return validator.New().Struct(o)
+}
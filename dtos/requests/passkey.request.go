@@ -0,0 +1,67 @@
+package requests
+
+import (
+	"encoding/json"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// PasskeyBeginRegistrationRequest confirms the caller before a passkey is added: the current password,
+// and a TOTP code when two-factor authentication is enabled.
+type PasskeyBeginRegistrationRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"omitempty,numeric,len=6"`
+}
+
+// NewPasskeyBeginRegistrationRequest creates a new instance of PasskeyBeginRegistrationRequest.
+func NewPasskeyBeginRegistrationRequest(password, code string) *PasskeyBeginRegistrationRequest {
+	return &PasskeyBeginRegistrationRequest{
+		Password: password,
+		Code:     code,
+	}
+}
+
+// Validate validates the PasskeyBeginRegistrationRequest fields.
+func (p *PasskeyBeginRegistrationRequest) Validate() error {
+	return validator.New().Struct(p)
+}
+
+// PasskeyRegistrationRequest represents the authenticator's answer to a registration ceremony.
+type PasskeyRegistrationRequest struct {
+	CeremonyID string          `json:"ceremony_id" validate:"required,uuid"`
+	Name       string          `json:"name" validate:"required,max=100"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
+
+// NewPasskeyRegistrationRequest creates a new instance of PasskeyRegistrationRequest.
+func NewPasskeyRegistrationRequest(ceremonyID, name string, credential json.RawMessage) *PasskeyRegistrationRequest {
+	return &PasskeyRegistrationRequest{
+		CeremonyID: ceremonyID,
+		Name:       name,
+		Credential: credential,
+	}
+}
+
+// Validate validates the PasskeyRegistrationRequest fields.
+func (p *PasskeyRegistrationRequest) Validate() error {
+	return validator.New().Struct(p)
+}
+
+// PasskeyLoginRequest represents the authenticator's answer to a login ceremony.
+type PasskeyLoginRequest struct {
+	CeremonyID string          `json:"ceremony_id" validate:"required,uuid"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
+
+// NewPasskeyLoginRequest creates a new instance of PasskeyLoginRequest.
+func NewPasskeyLoginRequest(ceremonyID string, credential json.RawMessage) *PasskeyLoginRequest {
+	return &PasskeyLoginRequest{
+		CeremonyID: ceremonyID,
+		Credential: credential,
+	}
+}
+
+// Validate validates the PasskeyLoginRequest fields.
+func (p *PasskeyLoginRequest) Validate() error {
+	return validator.New().Struct(p)
+}
@@ -0,0 +1,61 @@
+package requests
+
+import "github.com/go-playground/validator/v10"
+
+// UpdateProfileRequest represents a partial update of the caller's profile. Changing the email
+// must be confirmed with the current password.
+type UpdateProfileRequest struct {
+	Name            string `json:"name" validate:"omitempty,min=3,max=100"`
+	Email           string `json:"email" validate:"omitempty,email"`
+	CurrentPassword string `json:"current_password" validate:"required_with=Email"`
+}
+
+// NewUpdateProfileRequest creates a new instance of UpdateProfileRequest.
+func NewUpdateProfileRequest(name, email, currentPassword string) *UpdateProfileRequest {
+	return &UpdateProfileRequest{
+		Name:            name,
+		Email:           email,
+		CurrentPassword: currentPassword,
+	}
+}
+
+// Validate validates the UpdateProfileRequest fields.
+func (u *UpdateProfileRequest) Validate() error {
+	return validator.New().Struct(u)
+}
+
+// ChangePasswordRequest represents a password change confirmed with the current password.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8"`
+}
+
+// NewChangePasswordRequest creates a new instance of ChangePasswordRequest.
+func NewChangePasswordRequest(currentPassword, newPassword string) *ChangePasswordRequest {
+	return &ChangePasswordRequest{
+		CurrentPassword: currentPassword,
+		NewPassword:     newPassword,
+	}
+}
+
+// Validate validates the ChangePasswordRequest fields.
+func (c *ChangePasswordRequest) Validate() error {
+	return validator.New().Struct(c)
+}
+
+// DeleteAccountRequest represents an account deletion confirmed with the current password.
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// NewDeleteAccountRequest creates a new instance of DeleteAccountRequest.
+func NewDeleteAccountRequest(password string) *DeleteAccountRequest {
+	return &DeleteAccountRequest{
+		Password: password,
+	}
+}
+
+// Validate validates the DeleteAccountRequest fields.
+func (d *DeleteAccountRequest) Validate() error {
+	return validator.New().Struct(d)
+}
@@ -0,0 +1,35 @@
+package requests
+
+import (
+	"fmt"
+	"ticket-booking/entities"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// RoleRequest represents a request to replace the roles of an account.
+type RoleRequest struct {
+	Roles []string `json:"roles" validate:"required,min=1,dive,required"`
+}
+
+// NewRoleRequest creates a new instance of RoleRequest.
+func NewRoleRequest(roles []string) *RoleRequest {
+	return &RoleRequest{
+		Roles: roles,
+	}
+}
+
+// Validate validates the RoleRequest fields and ensures every role is known.
+func (r *RoleRequest) Validate() error {
+	if err := validator.New().Struct(r); err != nil {
+		return err
+	}
+
+	for _, role := range r.Roles {
+		if !entities.Role(role).IsValid() {
+			return fmt.Errorf("unknown role: %s", role)
+		}
+	}
+
+	return nil
+}
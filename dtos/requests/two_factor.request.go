@@ -0,0 +1,42 @@
+package requests
+
+import "github.com/go-playground/validator/v10"
+
+// TwoFactorCodeRequest represents a request carrying a TOTP code.
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required,numeric,len=6"`
+}
+
+// NewTwoFactorCodeRequest creates a new instance of TwoFactorCodeRequest.
+func NewTwoFactorCodeRequest(code string) *TwoFactorCodeRequest {
+	return &TwoFactorCodeRequest{
+		Code: code,
+	}
+}
+
+// Validate validates the TwoFactorCodeRequest fields.
+func (t *TwoFactorCodeRequest) Validate() error {
+	return validator.New().Struct(t)
+}
+
+// TwoFactorVerifyRequest represents the second step of a two-factor sign-in.
+// Either a TOTP code or a recovery code must be given.
+type TwoFactorVerifyRequest struct {
+	Challenge    string `json:"challenge" validate:"required"`
+	Code         string `json:"code" validate:"required_without=RecoveryCode,omitempty,numeric,len=6"`
+	RecoveryCode string `json:"recovery_code" validate:"required_without=Code"`
+}
+
+// NewTwoFactorVerifyRequest creates a new instance of TwoFactorVerifyRequest.
+func NewTwoFactorVerifyRequest(challenge, code, recoveryCode string) *TwoFactorVerifyRequest {
+	return &TwoFactorVerifyRequest{
+		Challenge:    challenge,
+		Code:         code,
+		RecoveryCode: recoveryCode,
+	}
+}
+
+// Validate validates the TwoFactorVerifyRequest fields.
+func (t *TwoFactorVerifyRequest) Validate() error {
+	return validator.New().Struct(t)
+}
@@ -0,0 +1,17 @@
+package responses
+
+import "ticket-booking/entities"
+
+type AccountResponse struct {
+	Status  int                 `json:"status"`
+	Message string              `json:"message"`
+	Data    []*entities.Account `json:"data,omitempty"`
+}
+
+func NewAccountResponse(status int, message string, data []*entities.Account) *AccountResponse {
+	return &AccountResponse{
+		Status:  status,
+		Message: message,
+		Data:    data,
+	}
+}
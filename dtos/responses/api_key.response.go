@@ -0,0 +1,41 @@
+package responses
+
+import "ticket-booking/entities"
+
+type APIKeyResponse struct {
+	Status  int                `json:"status"`
+	Message string             `json:"message"`
+	Data    []*entities.APIKey `json:"data,omitempty"`
+}
+
+func NewAPIKeyResponse(status int, message string, data []*entities.APIKey) *APIKeyResponse {
+	return &APIKeyResponse{
+		Status:  status,
+		Message: message,
+		Data:    data,
+	}
+}
+
+// CreatedAPIKeyResponse carries a newly created key, the only time it is ever shown.
+type CreatedAPIKeyResponse struct {
+	Status  int            `json:"status"`
+	Message string         `json:"message"`
+	Data    *CreatedAPIKey `json:"data,omitempty"`
+}
+
+// CreatedAPIKey is the stored key along with its clear-text value.
+type CreatedAPIKey struct {
+	*entities.APIKey
+	Key string `json:"key"`
+}
+
+func NewCreatedAPIKeyResponse(status int, message string, key string, model *entities.APIKey) *CreatedAPIKeyResponse {
+	return &CreatedAPIKeyResponse{
+		Status:  status,
+		Message: message,
+		Data: &CreatedAPIKey{
+			APIKey: model,
+			Key:    key,
+		},
+	}
+}
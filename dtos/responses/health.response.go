@@ -0,0 +1,14 @@
+package responses
+
+// HealthResponse reports the readiness of the application and of each component it depends on.
+type HealthResponse struct {
+	Status     string                      `json:"status"`
+	Components map[string]*ComponentHealth `json:"components,omitempty"`
+}
+
+// ComponentHealth is the outcome of a single readiness check.
+type ComponentHealth struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
@@ -0,0 +1,19 @@
+package responses
+
+// JWKSResponse is a JSON Web Key Set (RFC 7517) publishing the token verification keys.
+type JWKSResponse struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK is a single public key of the set.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
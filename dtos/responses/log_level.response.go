@@ -0,0 +1,22 @@
+package responses
+
+// LogLevelResponse represents the current log level.
+type LogLevelResponse struct {
+	Status  int       `json:"status"`
+	Message string    `json:"message"`
+	Data    *LogLevel `json:"data,omitempty"`
+}
+
+// LogLevel holds the name of the log level.
+type LogLevel struct {
+	Level string `json:"level"`
+}
+
+// NewLogLevelResponse creates a new instance of LogLevelResponse.
+func NewLogLevelResponse(status int, message, level string) *LogLevelResponse {
+	return &LogLevelResponse{
+		Status:  status,
+		Message: message,
+		Data:    &LogLevel{Level: level},
+	}
+}
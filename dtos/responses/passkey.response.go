@@ -0,0 +1,45 @@
+package responses
+
+import (
+	"ticket-booking/entities"
+
+	"github.com/google/uuid"
+)
+
+type PasskeyResponse struct {
+	Status  int                 `json:"status"`
+	Message string              `json:"message"`
+	Data    []*entities.Passkey `json:"data,omitempty"`
+}
+
+func NewPasskeyResponse(status int, message string, data []*entities.Passkey) *PasskeyResponse {
+	return &PasskeyResponse{
+		Status:  status,
+		Message: message,
+		Data:    data,
+	}
+}
+
+// PasskeyCeremonyResponse carries the options to pass to navigator.credentials.create() or .get(),
+// and the ceremony ID to send back with the authenticator's answer.
+type PasskeyCeremonyResponse struct {
+	Status  int              `json:"status"`
+	Message string           `json:"message"`
+	Data    *PasskeyCeremony `json:"data,omitempty"`
+}
+
+type PasskeyCeremony struct {
+	CeremonyID uuid.UUID `json:"ceremony_id"`
+	Options    any       `json:"options"`
+}
+
+func NewPasskeyCeremonyResponse(status int, message string, ceremonyID uuid.UUID, options any) *PasskeyCeremonyResponse {
+	return &PasskeyCeremonyResponse{
+		Status:  status,
+		Message: message,
+		Data: &PasskeyCeremony{
+			CeremonyID: ceremonyID,
+			Options:    options,
+		},
+	}
+}
@@ -0,0 +1,45 @@
+package responses
+
+import (
+	"time"
+
+	"ticket-booking/entities"
+
+	"github.com/google/uuid"
+)
+
+// ProfileResponse represents the caller's own account.
+type ProfileResponse struct {
+	Status  int      `json:"status"`
+	Message string   `json:"message"`
+	Data    *Profile `json:"data,omitempty"`
+}
+
+// Profile is the self-service view of an account.
+type Profile struct {
+	ID               uuid.UUID `json:"id"`
+	Name             string    `json:"name"`
+	Email            string    `json:"email"`
+	EmailVerified    bool      `json:"email_verified"`
+	PendingEmail     string    `json:"pending_email,omitempty"`
+	Roles            []string  `json:"roles"`
+	TwoFactorEnabled bool      `json:"two_factor_enabled"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+func NewProfileResponse(status int, message string, account *entities.Account) *ProfileResponse {
+	return &ProfileResponse{
+		Status:  status,
+		Message: message,
+		Data: &Profile{
+			ID:               account.ID,
+			Name:             account.Name,
+			Email:            account.Email,
+			EmailVerified:    account.IsEmailVerified(),
+			PendingEmail:     account.PendingEmail.String,
+			Roles:            account.Roles,
+			TwoFactorEnabled: account.TOTPEnabled,
+			CreatedAt:        account.CreatedAt,
+		},
+	}
+}
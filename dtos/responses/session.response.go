@@ -0,0 +1,17 @@
+package responses
+
+import "ticket-booking/entities"
+
+type SessionResponse struct {
+	Status  int                 `json:"status"`
+	Message string              `json:"message"`
+	Data    []*entities.Session `json:"data,omitempty"`
+}
+
+func NewSessionResponse(status int, message string, data []*entities.Session) *SessionResponse {
+	return &SessionResponse{
+		Status:  status,
+		Message: message,
+		Data:    data,
+	}
+}
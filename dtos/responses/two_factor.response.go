@@ -0,0 +1,71 @@
+package responses
+
+import "time"
+
+// TwoFactorEnrollmentResponse represents the secret to add to an authenticator app.
+type TwoFactorEnrollmentResponse struct {
+	Status  int                  `json:"status"`
+	Message string               `json:"message"`
+	Data    *TwoFactorEnrollment `json:"data,omitempty"`
+}
+
+// TwoFactorEnrollment holds the secret, its otpauth URI and the URI as a PNG QR code.
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+	QRCode []byte `json:"qrcode"`
+}
+
+// RecoveryCodesResponse represents recovery codes, shown only once.
+type RecoveryCodesResponse struct {
+	Status  int      `json:"status"`
+	Message string   `json:"message"`
+	Data    []string `json:"data,omitempty"`
+}
+
+// ChallengeResponse is returned by sign-in when a second factor is required.
+type ChallengeResponse struct {
+	Status  int        `json:"status"`
+	Message string     `json:"message"`
+	Data    *Challenge `json:"data,omitempty"`
+}
+
+// Challenge holds the token to present together with the second factor.
+type Challenge struct {
+	Challenge  string    `json:"challenge"`
+	ExpiryDate time.Time `json:"expiry"`
+}
+
+// NewTwoFactorEnrollmentResponse creates a new instance of TwoFactorEnrollmentResponse.
+func NewTwoFactorEnrollmentResponse(status int, message, secret, uri string, qrcode []byte) *TwoFactorEnrollmentResponse {
+	return &TwoFactorEnrollmentResponse{
+		Status:  status,
+		Message: message,
+		Data: &TwoFactorEnrollment{
+			Secret: secret,
+			URI:    uri,
+			QRCode: qrcode,
+		},
+	}
+}
+
+// NewRecoveryCodesResponse creates a new instance of RecoveryCodesResponse.
+func NewRecoveryCodesResponse(status int, message string, codes []string) *RecoveryCodesResponse {
+	return &RecoveryCodesResponse{
+		Status:  status,
+		Message: message,
+		Data:    codes,
+	}
+}
+
+// NewChallengeResponse creates a new instance of ChallengeResponse.
+func NewChallengeResponse(status int, message, challenge string, expiry time.Time) *ChallengeResponse {
+	return &ChallengeResponse{
+		Status:  status,
+		Message: message,
+		Data: &Challenge{
+			Challenge:  challenge,
+			ExpiryDate: expiry,
+		},
+	}
+}
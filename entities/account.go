@@ -1,27 +1,56 @@
 package entities
 
 import (
+	"database/sql"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type Account struct {
-	ID        uuid.UUID `db:"id" json:"id" validate:"required,uuid"`
-	Name      string    `db:"name" json:"name" validate:"required,min=3,max=100"`
-	Email     string    `db:"email" json:"email" validate:"required,email"`
-	Password  string    `db:"password" json:"password" validate:"required,min=8"`
-	CreatedAt time.Time `db:"created_at" json:"created_at" validate:"required"`
-	UpdatedAt time.Time `db:"updated_at" json:"updated_at" validate:"required"`
+	ID              uuid.UUID      `db:"id" json:"id" validate:"required,uuid"`
+	Name            string         `db:"name" json:"name" validate:"required,min=3,max=100"`
+	Email           string         `db:"email" json:"email" validate:"required,email"`
+	EmailVerifiedAt sql.NullTime   `db:"email_verified_at" json:"-"`
+	PendingEmail    sql.NullString `db:"pending_email" json:"-"`
+	Password        string         `db:"password" json:"-" validate:"required,min=8"`
+	Roles           pq.StringArray `db:"roles" json:"roles" validate:"required"`
+	TOTPSecret      sql.NullString `db:"totp_secret" json:"-"`
+	TOTPEnabled     bool           `db:"totp_enabled" json:"two_factor_enabled"`
+	TOTPLastStep    int64          `db:"totp_last_step" json:"-"`
+	FailedSignIns   int            `db:"failed_sign_ins" json:"failed_sign_ins"`
+	LockedUntil     *time.Time     `db:"locked_until" json:"locked_until,omitempty"`
+	CreatedAt       time.Time      `db:"created_at" json:"created_at" validate:"required"`
+	UpdatedAt       time.Time      `db:"updated_at" json:"updated_at" validate:"required"`
+	DeletedAt       sql.NullTime   `db:"deleted_at" json:"-"`
 }
 
 func NewAccount(name, email, password string) *Account {
 	return &Account{
 		ID:        uuid.New(),
 		Name:      name,
-		Email:     email,
+		Email:     NormalizeEmail(email),
 		Password:  password,
+		Roles:     DefaultRoles(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 }
+
+// NormalizeEmail returns the form emails are stored and looked up in. Addresses are compared
+// case-insensitively, so an account cannot be registered twice, or missed by a lookup, by changing case.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// IsEmailVerified reports whether the account confirmed ownership of its email address.
+func (a *Account) IsEmailVerified() bool {
+	return a.EmailVerifiedAt.Valid
+}
+
+// IsLocked reports whether sign-in is temporarily refused after too many failed attempts.
+func (a *Account) IsLocked(now time.Time) bool {
+	return a.LockedUntil != nil && a.LockedUntil.After(now)
+}
@@ -0,0 +1,38 @@
+package entities
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TokenPurpose restricts what a single-use account token can be exchanged for.
+type TokenPurpose string
+
+const (
+	TokenPurposeEmailVerification TokenPurpose = "email_verification"
+	TokenPurposePasswordReset     TokenPurpose = "password_reset"
+	TokenPurposeMagicLink         TokenPurpose = "magic_link"
+	TokenPurposeEmailChange       TokenPurpose = "email_change"
+)
+
+// AccountToken records a signed single-use token sent by email, so it can be consumed only once.
+type AccountToken struct {
+	ID        uuid.UUID    `db:"id"`
+	AccountID uuid.UUID    `db:"account_id"`
+	Purpose   TokenPurpose `db:"purpose"`
+	ExpiresAt time.Time    `db:"expires_at"`
+	UsedAt    sql.NullTime `db:"used_at"`
+	CreatedAt time.Time    `db:"created_at"`
+}
+
+func NewAccountToken(accountID uuid.UUID, purpose TokenPurpose, expiresAt time.Time) *AccountToken {
+	return &AccountToken{
+		ID:        uuid.New(),
+		AccountID: accountID,
+		Purpose:   purpose,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,43 @@
+package entities
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// APIKey is a long-lived credential owned by an account, for scanners, jobs and integrations.
+// Only the hash of the key is stored; the prefix identifies it in listings.
+type APIKey struct {
+	ID         uuid.UUID      `db:"id" json:"id"`
+	AccountID  uuid.UUID      `db:"account_id" json:"-"`
+	Name       string         `db:"name" json:"name"`
+	Prefix     string         `db:"prefix" json:"prefix"`
+	KeyHash    string         `db:"key_hash" json:"-"`
+	Scopes     pq.StringArray `db:"scopes" json:"scopes"`
+	ExpiresAt  *time.Time     `db:"expires_at" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time     `db:"last_used_at" json:"last_used_at,omitempty"`
+	RevokedAt  sql.NullTime   `db:"revoked_at" json:"-"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+	OwnerRoles pq.StringArray `db:"owner_roles" json:"-"`
+}
+
+func NewAPIKey(accountID uuid.UUID, name, prefix, keyHash string, scopes []string, expiresAt *time.Time) *APIKey {
+	return &APIKey{
+		ID:        NewPublicID(),
+		AccountID: accountID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   keyHash,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+}
+
+// IsExpired reports whether the key had an expiry and it has passed.
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !k.ExpiresAt.After(now)
+}
@@ -2,10 +2,13 @@ package entities
 
 import (
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Event struct {
-	ID        uint64    `db:"id" json:"id" valid:"uuid"`
+	ID        uint64    `db:"id" json:"-" valid:"uint"`
+	PublicID  uuid.UUID `db:"public_id" json:"id" valid:"uuid"`
 	Title     string    `db:"title" json:"title" valid:"string,required"`
 	Location  string    `db:"location" json:"location" valid:"string,required"`
 	Date      time.Time `db:"date" json:"date" valid:"required"`
@@ -15,6 +18,7 @@ type Event struct {
 
 func NewEvent(title, location string, date time.Time) *Event {
 	return &Event{
+		PublicID:  NewPublicID(),
 		Title:     title,
 		Location:  location,
 		Date:      date,
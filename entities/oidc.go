@@ -0,0 +1,50 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OIDCState holds what an authorization request needs to be completed: the nonce expected in the
+// ID token and the PKCE verifier. It is looked up by the state parameter and consumed once.
+type OIDCState struct {
+	State        string    `db:"state"`
+	Provider     string    `db:"provider"`
+	Nonce        string    `db:"nonce"`
+	CodeVerifier string    `db:"code_verifier"`
+	ExpiresAt    time.Time `db:"expires_at"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+func NewOIDCState(state, provider, nonce, codeVerifier string, expiresAt time.Time) *OIDCState {
+	return &OIDCState{
+		State:        state,
+		Provider:     provider,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    expiresAt,
+		CreatedAt:    time.Now(),
+	}
+}
+
+// AccountIdentity links an account to the subject of an external identity provider.
+type AccountIdentity struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	AccountID uuid.UUID `db:"account_id" json:"-"`
+	Provider  string    `db:"provider" json:"provider"`
+	Subject   string    `db:"subject" json:"-"`
+	Email     string    `db:"email" json:"email"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+func NewAccountIdentity(accountID uuid.UUID, provider, subject, email string) *AccountIdentity {
+	return &AccountIdentity{
+		ID:        uuid.New(),
+		AccountID: accountID,
+		Provider:  provider,
+		Subject:   subject,
+		Email:     email,
+		CreatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,73 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Passkey is a WebAuthn credential registered by an account. The sign count reported by the
+// authenticator is tracked to detect cloned credentials.
+type Passkey struct {
+	ID              uuid.UUID      `db:"id" json:"id"`
+	AccountID       uuid.UUID      `db:"account_id" json:"-"`
+	Name            string         `db:"name" json:"name"`
+	CredentialID    []byte         `db:"credential_id" json:"-"`
+	PublicKey       []byte         `db:"public_key" json:"-"`
+	AttestationType string         `db:"attestation_type" json:"-"`
+	AAGUID          []byte         `db:"aaguid" json:"-"`
+	SignCount       int64          `db:"sign_count" json:"-"`
+	Transports      pq.StringArray `db:"transports" json:"-"`
+	BackupEligible  bool           `db:"backup_eligible" json:"backup_eligible"`
+	BackupState     bool           `db:"backup_state" json:"backup_state"`
+	CreatedAt       time.Time      `db:"created_at" json:"created_at"`
+	LastUsedAt      *time.Time     `db:"last_used_at" json:"last_used_at,omitempty"`
+}
+
+func NewPasskey(accountID uuid.UUID, name string, credentialID, publicKey []byte, attestationType string, aaguid []byte, signCount uint32, transports []string, backupEligible, backupState bool) *Passkey {
+	return &Passkey{
+		ID:              uuid.New(),
+		AccountID:       accountID,
+		Name:            name,
+		CredentialID:    credentialID,
+		PublicKey:       publicKey,
+		AttestationType: attestationType,
+		AAGUID:          aaguid,
+		SignCount:       int64(signCount),
+		Transports:      transports,
+		BackupEligible:  backupEligible,
+		BackupState:     backupState,
+		CreatedAt:       time.Now(),
+	}
+}
+
+// CeremonyKind tells a registration from a login ceremony.
+type CeremonyKind string
+
+const (
+	CeremonyRegistration CeremonyKind = "registration"
+	CeremonyLogin        CeremonyKind = "login"
+)
+
+// PasskeyCeremony holds the server side of a WebAuthn ceremony, the challenge in particular,
+// between the options sent to the browser and the authenticator's response. It is consumed once.
+type PasskeyCeremony struct {
+	ID          uuid.UUID     `db:"id"`
+	AccountID   uuid.NullUUID `db:"account_id"`
+	Kind        CeremonyKind  `db:"kind"`
+	SessionData []byte        `db:"session_data"`
+	ExpiresAt   time.Time     `db:"expires_at"`
+	CreatedAt   time.Time     `db:"created_at"`
+}
+
+func NewPasskeyCeremony(accountID uuid.NullUUID, kind CeremonyKind, sessionData []byte, expiresAt time.Time) *PasskeyCeremony {
+	return &PasskeyCeremony{
+		ID:          uuid.New(),
+		AccountID:   accountID,
+		Kind:        kind,
+		SessionData: sessionData,
+		ExpiresAt:   expiresAt,
+		CreatedAt:   time.Now(),
+	}
+}
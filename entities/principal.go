@@ -0,0 +1,78 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Principal is the authenticated caller of a request, as established by the auth middleware.
+type Principal struct {
+	AccountID uuid.UUID
+	Roles     []string
+	Scopes    []Permission
+	SessionID uuid.UUID
+	TokenID   uuid.UUID
+	ExpiresAt time.Time
+	APIKeyID  uuid.UUID
+}
+
+// NewPrincipal creates a principal whose scopes are the permissions granted by its roles.
+func NewPrincipal(accountID uuid.UUID, roles []string, sessionID, tokenID uuid.UUID, expiresAt time.Time) *Principal {
+	return &Principal{
+		AccountID: accountID,
+		Roles:     roles,
+		Scopes:    PermissionsOf(roles),
+		SessionID: sessionID,
+		TokenID:   tokenID,
+		ExpiresAt: expiresAt,
+	}
+}
+
+// NewAPIKeyPrincipal creates a principal for an API key. It holds no roles, only the key's scopes
+// that its owner is still granted, so role-guarded routes stay reserved to signed-in humans.
+func NewAPIKeyPrincipal(key *APIKey) *Principal {
+	granted := make(map[Permission]bool)
+	for _, permission := range PermissionsOf(key.OwnerRoles) {
+		granted[permission] = true
+	}
+
+	scopes := make([]Permission, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		if granted[Permission(scope)] {
+			scopes = append(scopes, Permission(scope))
+		}
+	}
+
+	principal := &Principal{
+		AccountID: key.AccountID,
+		Scopes:    scopes,
+		APIKeyID:  key.ID,
+	}
+	if key.ExpiresAt != nil {
+		principal.ExpiresAt = *key.ExpiresAt
+	}
+
+	return principal
+}
+
+// IsAPIKey reports whether the principal authenticated with an API key rather than a session.
+func (p *Principal) IsAPIKey() bool {
+	return p.APIKeyID != uuid.Nil
+}
+
+// HasRole reports whether the principal holds one of the roles. Admins satisfy every role.
+func (p *Principal) HasRole(roles ...Role) bool {
+	return HasRole(p.Roles, roles...)
+}
+
+// HasScope reports whether the principal is allowed the permission.
+func (p *Principal) HasScope(permission Permission) bool {
+	for _, scope := range p.Scopes {
+		if scope == permission {
+			return true
+		}
+	}
+
+	return false
+}
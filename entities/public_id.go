@@ -0,0 +1,22 @@
+package entities
+
+import (
+	"github.com/google/uuid"
+)
+
+// NewPublicID returns a UUIDv7 to be exposed in URLs instead of the internal SERIAL key.
+// UUIDv7 values are opaque to clients but still sort by creation time.
+func NewPublicID() uuid.UUID {
+	id, err := uuid.NewV7()
+	if err != nil {
+		// NewV7 only fails when the random source does, fall back to a random UUID.
+		return uuid.New()
+	}
+
+	return id
+}
+
+// ParsePublicID parses an identifier received from a client.
+func ParsePublicID(value string) (uuid.UUID, error) {
+	return uuid.Parse(value)
+}
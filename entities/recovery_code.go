@@ -0,0 +1,26 @@
+package entities
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecoveryCode is a single-use code accepted in place of a TOTP code. Only its hash is stored.
+type RecoveryCode struct {
+	ID        uuid.UUID    `db:"id"`
+	AccountID uuid.UUID    `db:"account_id"`
+	CodeHash  string       `db:"code_hash"`
+	UsedAt    sql.NullTime `db:"used_at"`
+	CreatedAt time.Time    `db:"created_at"`
+}
+
+func NewRecoveryCode(accountID uuid.UUID, codeHash string) *RecoveryCode {
+	return &RecoveryCode{
+		ID:        uuid.New(),
+		AccountID: accountID,
+		CodeHash:  codeHash,
+		CreatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,116 @@
+package entities
+
+// Role is a named set of permissions assigned to an account.
+type Role string
+
+// Permission is a single action an authenticated caller may perform.
+type Permission string
+
+const (
+	RoleAdmin     Role = "admin"
+	RoleOrganizer Role = "organizer"
+	RoleStaff     Role = "staff"
+	RoleCustomer  Role = "customer"
+)
+
+const (
+	PermissionEventsRead     Permission = "events:read"
+	PermissionEventsWrite    Permission = "events:write"
+	PermissionTicketsRead    Permission = "tickets:read"
+	PermissionTicketsWrite   Permission = "tickets:write"
+	PermissionTicketsScan    Permission = "tickets:scan"
+	PermissionReportsRead    Permission = "reports:read"
+	PermissionAccountsManage Permission = "accounts:manage"
+)
+
+var customerPermissions = []Permission{
+	PermissionEventsRead,
+	PermissionTicketsRead,
+	PermissionTicketsWrite,
+}
+
+var staffPermissions = append([]Permission{
+	PermissionTicketsScan,
+}, customerPermissions...)
+
+var organizerPermissions = append([]Permission{
+	PermissionEventsWrite,
+	PermissionReportsRead,
+}, staffPermissions...)
+
+var adminPermissions = append([]Permission{
+	PermissionAccountsManage,
+}, organizerPermissions...)
+
+// rolePermissions maps every role to the permissions it grants.
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin:     adminPermissions,
+	RoleOrganizer: organizerPermissions,
+	RoleStaff:     staffPermissions,
+	RoleCustomer:  customerPermissions,
+}
+
+// DefaultRoles are the roles given to newly registered accounts.
+func DefaultRoles() []string {
+	return []string{string(RoleCustomer)}
+}
+
+// IsValid reports whether the role is one of the known roles.
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Permissions returns the permissions granted by the role.
+func (r Role) Permissions() []Permission {
+	return rolePermissions[r]
+}
+
+// HasRole reports whether any of the given roles matches one of the allowed roles.
+// Admins implicitly satisfy every role check.
+func HasRole(roles []string, allowed ...Role) bool {
+	for _, role := range roles {
+		if Role(role) == RoleAdmin {
+			return true
+		}
+		for _, a := range allowed {
+			if Role(role) == a {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// PermissionsOf returns the distinct permissions granted by the given roles.
+func PermissionsOf(roles []string) []Permission {
+	seen := make(map[Permission]bool)
+	permissions := make([]Permission, 0)
+
+	for _, role := range roles {
+		for _, permission := range Role(role).Permissions() {
+			if !seen[permission] {
+				seen[permission] = true
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+
+	return permissions
+}
+
+// apiKeyScopes are the permissions an API key may carry. Managing accounts is reserved to humans.
+var apiKeyScopes = map[Permission]bool{
+	PermissionEventsRead:   true,
+	PermissionEventsWrite:  true,
+	PermissionTicketsRead:  true,
+	PermissionTicketsWrite: true,
+	PermissionTicketsScan:  true,
+	PermissionReportsRead:  true,
+}
+
+// IsAPIKeyScope reports whether the permission may be granted to an API key.
+func (p Permission) IsAPIKeyScope() bool {
+	return apiKeyScopes[p]
+}
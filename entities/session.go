@@ -0,0 +1,60 @@
+package entities
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is a refresh token family, one per signed-in device.
+// Every refresh rotates the token but keeps the session.
+type Session struct {
+	ID         uuid.UUID    `db:"id" json:"id"`
+	AccountID  uuid.UUID    `db:"account_id" json:"-"`
+	Device     string       `db:"device" json:"device"`
+	IPAddress  string       `db:"ip_address" json:"ip_address"`
+	CreatedAt  time.Time    `db:"created_at" json:"created_at"`
+	LastUsedAt time.Time    `db:"last_used_at" json:"last_used_at"`
+	RevokedAt  sql.NullTime `db:"revoked_at" json:"-"`
+	Current    bool         `db:"-" json:"current"`
+}
+
+// RefreshToken is a single member of a session's token family. Only its hash is stored.
+type RefreshToken struct {
+	ID        uuid.UUID    `db:"id"`
+	SessionID uuid.UUID    `db:"session_id"`
+	TokenHash string       `db:"token_hash"`
+	ExpiresAt time.Time    `db:"expires_at"`
+	RotatedAt sql.NullTime `db:"rotated_at"`
+	CreatedAt time.Time    `db:"created_at"`
+}
+
+// maxDeviceLength is the size of the sessions.device column, in characters.
+const maxDeviceLength = 255
+
+func NewSession(accountID uuid.UUID, device, ipAddress string) *Session {
+	// The device is the raw User-Agent, which clients can make arbitrarily long
+	if runes := []rune(device); len(runes) > maxDeviceLength {
+		device = string(runes[:maxDeviceLength])
+	}
+
+	return &Session{
+		ID:         uuid.New(),
+		AccountID:  accountID,
+		Device:     device,
+		IPAddress:  ipAddress,
+		CreatedAt:  time.Now(),
+		LastUsedAt: time.Now(),
+	}
+}
+
+func NewRefreshToken(sessionID uuid.UUID, tokenHash string, expiresAt time.Time) *RefreshToken {
+	return &RefreshToken{
+		ID:        uuid.New(),
+		SessionID: sessionID,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+}
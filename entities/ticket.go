@@ -7,8 +7,9 @@ import (
 )
 
 type Ticket struct {
-	ID        uint64    `db:"id" json:"id" valid:"uint"`
-	EventID   uint64    `db:"event_id" json:"event_id" valid:"uint" relation:"event_id" fk:"id"`
+	ID        uint64    `db:"id" json:"-" valid:"uint"`
+	PublicID  uuid.UUID `db:"public_id" json:"id" valid:"uuid"`
+	EventID   uint64    `db:"event_id" json:"-" valid:"uint" relation:"event_id" fk:"id"`
 	Event     *Event    `db:"event" json:"event" valid:"-" relation:"event_id" fk:"id" `
 	AccountID uuid.UUID `db:"account_id" json:"account_id" valid:"uuid" relation:"account_id" fk:"id"`
 	Entered   bool      `db:"entered" json:"entered" valid:"required"`
@@ -18,6 +19,7 @@ type Ticket struct {
 
 func NewTicket(eventID uint64, accountID uuid.UUID) *Ticket {
 	return &Ticket{
+		PublicID:  NewPublicID(),
 		EventID:   eventID,
 		AccountID: accountID,
 		Entered:   false,
@@ -2,6 +2,10 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
 	"time"
 
 	"ticket-booking/configs/errs"
@@ -14,6 +18,8 @@ import (
 	"ticket-booking/services"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
 // AuthHandler defines methods for handling auth routes.
@@ -21,39 +27,55 @@ type AuthHandler interface {
 	SignIn(ctx *fiber.Ctx) error
 	SignUp(ctx *fiber.Ctx) error
 	Refresh(ctx *fiber.Ctx) error
+	Logout(ctx *fiber.Ctx) error
+	LogoutAll(ctx *fiber.Ctx) error
+	Sessions(ctx *fiber.Ctx) error
+	RevokeSession(ctx *fiber.Ctx) error
+	VerifyEmail(ctx *fiber.Ctx) error
+	ConfirmEmailChange(ctx *fiber.Ctx) error
+	ResendVerification(ctx *fiber.Ctx) error
+	ForgotPassword(ctx *fiber.Ctx) error
+	ResetPassword(ctx *fiber.Ctx) error
+	RequestMagicLink(ctx *fiber.Ctx) error
+	MagicLinkSignIn(ctx *fiber.Ctx) error
 }
 
 // authHandler is an implementation of AuthHandler that manages authentication routes.
 type authHandler struct {
-	repository   repositories.AccountRepository
-	tokenization services.Tokenization
-	cryptography services.Cryptography
+	repository    repositories.AccountRepository
+	tokenization  services.Tokenization
+	cryptography  services.Cryptography
+	accountTokens services.AccountTokens
+	accountMails  services.AccountMails
+	signInGuard   services.SignInGuard
+	jobs          services.Jobs
+	decoyHash     string
 }
 
 // SignUp handles the sign-up route, registering a new user.
 func (h *authHandler) SignUp(ctx *fiber.Ctx) error {
-	context, cancel := h.newContext()
+	context, cancel := h.newContext(ctx)
 	defer cancel()
 
 	var request requests.SignUpRequest
 	if err := ctx.BodyParser(&request); err != nil {
-		logs.Error("AuthHandler.SignUp: Failed to parse request body", err)
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.SignUp: Failed to parse request body", err)
 		return errs.NewBadRequest(ctx, "Invalid parameter")
 	}
 
 	if err := request.Validate(); err != nil {
-		logs.Error("AuthHandler.SignUp: Invalid request body", err)
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.SignUp: Invalid request body", err)
 		return errs.NewBadRequest(ctx, "Invalid parameter")
 	}
 
 	if _, err := h.repository.FindByEmail(context, request.Email); err == nil {
-		logs.Error("AuthHandler.SignUp: Email already exists", err)
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.SignUp: Email already exists", err)
 		return errs.NewBadRequest(ctx, "Email already exists")
 	}
 
 	hashedPassword, err := h.cryptography.EncryptPassword(request.Password)
 	if err != nil {
-		logs.Error("AuthHandler.SignUp: Failed to encrypt password", err)
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.SignUp: Failed to encrypt password", err)
 		return errs.NewInternalServerError(ctx, "Failed to sign up")
 	}
 
@@ -61,10 +83,13 @@ func (h *authHandler) SignUp(ctx *fiber.Ctx) error {
 
 	err = h.repository.SignUp(context, newAccount)
 	if err != nil {
-		logs.Error("AuthHandler.SignUp: Failed to create user", err)
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.SignUp: Failed to create user", err)
 		return errs.NewInternalServerError(ctx, "Failed to sign up")
 	}
 
+	// The account exists at this point, a failed email can be sent again through the resend route
+	h.sendVerification(context, newAccount)
+
 	return ctx.Status(fiber.StatusCreated).JSON(
 		responses.NewBaseResponse(
 			fiber.StatusCreated,
@@ -74,76 +99,96 @@ func (h *authHandler) SignUp(ctx *fiber.Ctx) error {
 
 // SignIn handles the sign-in route, authenticating a user.
 func (h *authHandler) SignIn(ctx *fiber.Ctx) error {
-	context, cancel := h.newContext()
+	context, cancel := h.newContext(ctx)
 	defer cancel()
 
 	var request requests.SignInRequest
 	if err := ctx.BodyParser(&request); err != nil {
-		logs.Error("AuthHandler.SignIn: Failed to parse request body", err)
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.SignIn: Failed to parse request body", err)
 		return errs.NewBadRequest(ctx, "Invalid parameter")
 	}
 
 	if err := request.Validate(); err != nil {
-		logs.Error("AuthHandler.SignIn: Invalid request body", err)
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.SignIn: Invalid request body", err)
 		return errs.NewBadRequest(ctx, "Invalid parameter")
 	}
 
-	account, err := h.repository.FindByEmail(context, request.Email)
-	if err != nil {
-		logs.Error("AuthHandler.SignIn: Account not found", err)
-		return errs.NewBadRequest(ctx, "Account not found")
+	if wait := h.signInGuard.Throttled(ctx.IP()); wait > 0 {
+		logs.WarnContext(ctx.UserContext(), "AuthHandler.SignIn: Too many failed attempts from client")
+		ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(wait.Seconds())+1))
+		return errs.NewTooManyRequests(ctx, "Too many sign-in attempts, try again later")
 	}
 
-	decryptedPassword, err := h.cryptography.VerifyPassword(request.Password, account.Password)
-	if err != nil {
-		logs.Error("AuthHandler.SignIn: Failed to verify password", err)
+	account, err := h.repository.FindByEmail(context, request.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.SignIn: Failed to retrieve account", err)
 		return errs.NewInternalServerError(ctx, "Failed to sign in")
 	}
 
-	if !decryptedPassword {
-		logs.Error("AuthHandler.SignIn: Incorrect password", err)
-		return errs.NewBadRequest(ctx, "Incorrect password")
+	// Unknown emails are checked against a decoy hash so they take as long as a wrong password,
+	// and every failure gets the same answer, so the response says nothing about which emails exist
+	hashedPassword := h.decoyHash
+	if account != nil {
+		hashedPassword = account.Password
 	}
 
-	token, err := h.tokenization.GenerateToken(account.ID.String())
+	decryptedPassword, needsRehash, err := h.cryptography.VerifyPassword(request.Password, hashedPassword)
 	if err != nil {
-		logs.Error("AuthHandler.SignIn: Failed to generate token", err)
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.SignIn: Failed to verify password", err)
 		return errs.NewInternalServerError(ctx, "Failed to sign in")
 	}
 
-	return ctx.Status(fiber.StatusOK).JSON(
-		responses.NewSignInResponse(
-			fiber.StatusOK,
-			"Sign-in successful",
-			[]*responses.TokenResponse{token},
-		))
+	if account == nil || !decryptedPassword || account.IsLocked(time.Now()) {
+		logs.WarnContext(ctx.UserContext(), "AuthHandler.SignIn: Invalid credentials")
+		h.signInGuard.Failed(context, ctx.IP(), account)
+		return errs.NewUnauthorized(ctx, "Invalid email or password")
+	}
+
+	// With two-factor authentication the failures are only cleared once the code is verified, otherwise
+	// knowing the password would be enough to reset the lockout between guesses of the code
+	if !account.TOTPEnabled {
+		h.signInGuard.Succeeded(context, ctx.IP(), account)
+	}
+
+	if needsRehash {
+		h.rehashPassword(context, account, request.Password)
+	}
+
+	return issueSession(context, ctx, h.tokenization, account, "AuthHandler.SignIn")
 }
 
 // Refresh handles token refresh requests.
 func (h *authHandler) Refresh(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
 	refreshToken := ctx.Get("Token")
 	if refreshToken == "" {
-		logs.Error("AuthHandler.Refresh: Missing refresh token in header", nil)
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.Refresh: Missing refresh token in header", nil)
 		return errs.NewBadRequest(ctx, "Missing refresh token")
 	}
 
-	userId := ctx.Get("UserId")
-	if userId == "" {
-		logs.Error("AuthHandler.Refresh: Missing user ID in header", nil)
-		return errs.NewBadRequest(ctx, "Missing user ID")
+	// Validate and rotate the refresh token
+	session, err := h.tokenization.VerifyRefreshToken(context, refreshToken)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.Refresh: Invalid refresh token", err)
+		if errors.Is(err, services.ErrRefreshTokenInvalid) || errors.Is(err, services.ErrRefreshTokenReused) {
+			return errs.NewUnauthorized(ctx, "Invalid refresh token")
+		}
+		return errs.NewInternalServerError(ctx, "Failed to refresh token")
 	}
 
-	// Validate the refresh token
-	valid, err := h.tokenization.VerifyRefreshToken(userId, refreshToken)
-	if err != nil || !valid {
-		logs.Error("AuthHandler.Refresh: Invalid refresh token", err)
+	// Roles are read again so changes made by an admin apply on the next refresh
+	account, err := h.repository.FindByID(context, session.AccountID)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.Refresh: Account not found", err)
 		return errs.NewUnauthorized(ctx, "Invalid refresh token")
 	}
 
-	// Generate a new token and refresh token
-	tokenResponse, err := h.tokenization.GenerateToken(userId)
+	// Generate a new token and the next refresh token of the session
+	tokenResponse, err := h.tokenization.GenerateSessionToken(context, session, account.Roles)
 	if err != nil {
-		logs.Error("AuthHandler.Refresh: Failed to generate new token", err)
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.Refresh: Failed to generate new token", err)
 		return errs.NewInternalServerError(ctx, "Failed to refresh token")
 	}
 
@@ -155,17 +200,467 @@ func (h *authHandler) Refresh(ctx *fiber.Ctx) error {
 		))
 }
 
+// Logout revokes the caller's token and the session it belongs to.
+func (h *authHandler) Logout(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	if err := h.tokenization.RevokeToken(context, middlewares.GetPrincipal(ctx)); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.Logout: Failed to revoke token", err)
+		return errs.NewInternalServerError(ctx, "Failed to log out")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewBaseResponse(
+			fiber.StatusOK,
+			"Logout successful",
+		))
+}
+
+// LogoutAll revokes every session of the caller, logging out all devices.
+func (h *authHandler) LogoutAll(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	principal := middlewares.GetPrincipal(ctx)
+
+	if err := h.tokenization.RevokeAllSessions(context, principal.AccountID); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.LogoutAll: Failed to revoke sessions", err)
+		return errs.NewInternalServerError(ctx, "Failed to log out")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewBaseResponse(
+			fiber.StatusOK,
+			"Logged out of all devices",
+		))
+}
+
+// Sessions lists the caller's active sessions, one per signed-in device.
+func (h *authHandler) Sessions(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	principal := middlewares.GetPrincipal(ctx)
+
+	sessions, err := h.tokenization.FindSessions(context, principal.AccountID)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.Sessions: Failed to retrieve sessions", err)
+		return errs.NewInternalServerError(ctx, "Failed to retrieve sessions")
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == principal.SessionID
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewSessionResponse(
+			fiber.StatusOK,
+			"Sessions retrieved successfully",
+			sessions,
+		))
+}
+
+// RevokeSession logs out one of the caller's sessions.
+func (h *authHandler) RevokeSession(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	principal := middlewares.GetPrincipal(ctx)
+
+	sessionID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.RevokeSession: Invalid ID parameter", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if err := h.tokenization.RevokeSession(context, principal.AccountID, sessionID); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			return errs.NewNotFound(ctx, "Session not found")
+		}
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.RevokeSession: Failed to revoke session", err)
+		return errs.NewInternalServerError(ctx, "Failed to revoke session")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewBaseResponse(
+			fiber.StatusOK,
+			"Session revoked successfully",
+		))
+}
+
+// VerifyEmail confirms the email address of the account the token was sent to.
+func (h *authHandler) VerifyEmail(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	var request requests.TokenRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.VerifyEmail: Failed to parse request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if err := request.Validate(); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.VerifyEmail: Invalid request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	accountID, err := h.accountTokens.Consume(context, request.Token, entities.TokenPurposeEmailVerification)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.VerifyEmail: Failed to consume token", err)
+		if errors.Is(err, services.ErrAccountTokenInvalid) {
+			return errs.NewBadRequest(ctx, "Invalid or expired token")
+		}
+		return errs.NewInternalServerError(ctx, "Failed to verify email")
+	}
+
+	if err := h.repository.MarkEmailVerified(context, accountID); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.VerifyEmail: Failed to mark email as verified", err)
+		return errs.NewInternalServerError(ctx, "Failed to verify email")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewBaseResponse(
+			fiber.StatusOK,
+			"Email verified successfully",
+		))
+}
+
+// ConfirmEmailChange swaps in the pending email of the account the token was sent to, which proves
+// the new address belongs to the caller.
+func (h *authHandler) ConfirmEmailChange(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	var request requests.TokenRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.ConfirmEmailChange: Failed to parse request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if err := request.Validate(); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.ConfirmEmailChange: Invalid request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	accountID, err := h.accountTokens.Consume(context, request.Token, entities.TokenPurposeEmailChange)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.ConfirmEmailChange: Failed to consume token", err)
+		if errors.Is(err, services.ErrAccountTokenInvalid) {
+			return errs.NewBadRequest(ctx, "Invalid or expired token")
+		}
+		return errs.NewInternalServerError(ctx, "Failed to change email")
+	}
+
+	changed, err := h.repository.ConfirmPendingEmail(context, accountID)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.ConfirmEmailChange: Failed to confirm pending email", err)
+		return errs.NewInternalServerError(ctx, "Failed to change email")
+	}
+
+	if !changed {
+		logs.WarnContext(ctx.UserContext(), "AuthHandler.ConfirmEmailChange: No pending email or already taken")
+		return errs.NewBadRequest(ctx, "Email already exists")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewBaseResponse(
+			fiber.StatusOK,
+			"Email changed successfully",
+		))
+}
+
+// ResendVerification sends a new verification email to the caller.
+func (h *authHandler) ResendVerification(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	account, err := h.repository.FindByID(context, middlewares.GetPrincipal(ctx).AccountID)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.ResendVerification: Failed to retrieve account", err)
+		return errs.NewInternalServerError(ctx, "Failed to send verification email")
+	}
+
+	if account.IsEmailVerified() {
+		return errs.NewBadRequest(ctx, "Email already verified")
+	}
+
+	if !h.sendVerification(context, account) {
+		return errs.NewInternalServerError(ctx, "Failed to send verification email")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewBaseResponse(
+			fiber.StatusOK,
+			"Verification email sent",
+		))
+}
+
+// ForgotPassword emails a password reset link. It answers the same way, and in the same time, whether
+// or not the email belongs to an account, so it cannot be used to discover accounts: the account is
+// looked up and the email sent by a background job.
+func (h *authHandler) ForgotPassword(ctx *fiber.Ctx) error {
+	var request requests.ForgotPasswordRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.ForgotPassword: Failed to parse request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if err := request.Validate(); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.ForgotPassword: Invalid request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	email := strings.Clone(request.Email)
+	h.jobs.Enqueue(ctx.UserContext(), "password reset email", func(context context.Context) {
+		h.sendAccountToken(context, email, entities.TokenPurposePasswordReset, h.accountMails.SendPasswordReset)
+	})
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewBaseResponse(
+			fiber.StatusOK,
+			"If the email belongs to an account, a reset link has been sent",
+		))
+}
+
+// ResetPassword sets a new password with a reset token and signs the account out of every device.
+func (h *authHandler) ResetPassword(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	var request requests.ResetPasswordRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.ResetPassword: Failed to parse request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if err := request.Validate(); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.ResetPassword: Invalid request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	accountID, err := h.accountTokens.Consume(context, request.Token, entities.TokenPurposePasswordReset)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.ResetPassword: Failed to consume token", err)
+		if errors.Is(err, services.ErrAccountTokenInvalid) {
+			return errs.NewBadRequest(ctx, "Invalid or expired token")
+		}
+		return errs.NewInternalServerError(ctx, "Failed to reset password")
+	}
+
+	hashedPassword, err := h.cryptography.EncryptPassword(request.Password)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.ResetPassword: Failed to encrypt password", err)
+		return errs.NewInternalServerError(ctx, "Failed to reset password")
+	}
+
+	if err := h.repository.UpdatePassword(context, accountID, hashedPassword); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.ResetPassword: Failed to update password", err)
+		return errs.NewInternalServerError(ctx, "Failed to reset password")
+	}
+
+	// Receiving the email proves ownership of the address as well
+	if err := h.repository.MarkEmailVerified(context, accountID); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.ResetPassword: Failed to mark email as verified", err)
+	}
+
+	if err := h.tokenization.RevokeAllSessions(context, accountID); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.ResetPassword: Failed to revoke sessions", err)
+		return errs.NewInternalServerError(ctx, "Failed to reset password")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewBaseResponse(
+			fiber.StatusOK,
+			"Password reset successfully",
+		))
+}
+
+// RequestMagicLink emails a single-use sign-in link. Like ForgotPassword, the answer is the same whether
+// or not the email belongs to an account, and requests past the per-account limit are silently dropped.
+func (h *authHandler) RequestMagicLink(ctx *fiber.Ctx) error {
+	var request requests.MagicLinkRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.RequestMagicLink: Failed to parse request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if err := request.Validate(); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.RequestMagicLink: Invalid request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	email := strings.Clone(request.Email)
+	h.jobs.Enqueue(ctx.UserContext(), "magic link email", func(context context.Context) {
+		h.sendAccountToken(context, email, entities.TokenPurposeMagicLink, h.accountMails.SendMagicLink)
+	})
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewBaseResponse(
+			fiber.StatusOK,
+			"If the email belongs to an account, a sign-in link has been sent",
+		))
+}
+
+// MagicLinkSignIn consumes a sign-in link and answers like SignIn. Following the link also
+// proves ownership of the email address.
+func (h *authHandler) MagicLinkSignIn(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	var request requests.TokenRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.MagicLinkSignIn: Failed to parse request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if err := request.Validate(); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.MagicLinkSignIn: Invalid request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	accountID, err := h.accountTokens.Consume(context, request.Token, entities.TokenPurposeMagicLink)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.MagicLinkSignIn: Failed to consume token", err)
+		if errors.Is(err, services.ErrAccountTokenInvalid) {
+			return errs.NewUnauthorized(ctx, "Invalid or expired link")
+		}
+		return errs.NewInternalServerError(ctx, "Failed to sign in")
+	}
+
+	account, err := h.repository.FindByID(context, accountID)
+	if err != nil || account.DeletedAt.Valid {
+		logs.ErrorContext(ctx.UserContext(), "AuthHandler.MagicLinkSignIn: Account not found", err)
+		return errs.NewUnauthorized(ctx, "Invalid or expired link")
+	}
+
+	if !account.IsEmailVerified() {
+		if err := h.repository.MarkEmailVerified(context, account.ID); err != nil {
+			logs.ErrorContext(ctx.UserContext(), "AuthHandler.MagicLinkSignIn: Failed to mark email as verified", err)
+		}
+	}
+
+	return issueSession(context, ctx, h.tokenization, account, "AuthHandler.MagicLinkSignIn")
+}
+
+// sendVerification issues a verification token and emails it, reporting whether it succeeded.
+func (h *authHandler) sendVerification(context context.Context, account *entities.Account) bool {
+	token, err := h.accountTokens.Issue(context, account.ID, entities.TokenPurposeEmailVerification)
+	if err != nil {
+		logs.ErrorContext(context, "AuthHandler.sendVerification: Failed to issue verification token", err)
+		return false
+	}
+
+	if err := h.accountMails.SendVerification(context, account, token); err != nil {
+		logs.ErrorContext(context, "AuthHandler.sendVerification: Failed to send verification email", err)
+		return false
+	}
+
+	return true
+}
+
+// sendAccountToken issues a token of the purpose to the account of the email, if any, and sends it.
+// It runs as a background job; requests past the purpose's limit are dropped.
+func (h *authHandler) sendAccountToken(context context.Context, email string, purpose entities.TokenPurpose, send func(context.Context, *entities.Account, string) error) {
+	account, err := h.repository.FindByEmail(context, email)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logs.ErrorContext(context, "AuthHandler.sendAccountToken: Failed to retrieve account", err)
+		}
+		return
+	}
+
+	token, err := h.accountTokens.Issue(context, account.ID, purpose)
+	if errors.Is(err, services.ErrAccountTokenRateLimited) {
+		logs.WarnContext(context, "AuthHandler.sendAccountToken: Too many tokens requested", zap.String("purpose", string(purpose)))
+		return
+	}
+	if err != nil {
+		logs.ErrorContext(context, "AuthHandler.sendAccountToken: Failed to issue token", err, zap.String("purpose", string(purpose)))
+		return
+	}
+
+	if err := send(context, account, token); err != nil {
+		logs.ErrorContext(context, "AuthHandler.sendAccountToken: Failed to send email", err, zap.String("purpose", string(purpose)))
+	}
+}
+
+// issueSession completes a sign-in once the first factor is verified, answering with a two-factor
+// challenge or a new session's tokens. Every first-factor sign-in goes through it, so none can skip
+// the second factor.
+func issueSession(context context.Context, ctx *fiber.Ctx, tokenization services.Tokenization, account *entities.Account, caller string) error {
+	// With two-factor authentication the first factor only earns a challenge, the session is
+	// issued by the two-factor verify route once a valid code is presented
+	if account.TOTPEnabled {
+		challenge, expiry, err := tokenization.GenerateChallengeToken(account.ID)
+		if err != nil {
+			logs.ErrorContext(ctx.UserContext(), caller+": Failed to generate challenge", err)
+			return errs.NewInternalServerError(ctx, "Failed to sign in")
+		}
+
+		return ctx.Status(fiber.StatusAccepted).JSON(
+			responses.NewChallengeResponse(
+				fiber.StatusAccepted,
+				"Two-factor authentication required",
+				challenge,
+				expiry,
+			))
+	}
+
+	token, err := tokenization.GenerateToken(context, account.ID.String(), account.Roles, ctx.Get(fiber.HeaderUserAgent), ctx.IP())
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), caller+": Failed to generate token", err)
+		return errs.NewInternalServerError(ctx, "Failed to sign in")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewSignInResponse(
+			fiber.StatusOK,
+			"Sign-in successful",
+			[]*responses.TokenResponse{token},
+		))
+}
+
+// rehashPassword upgrades a stored hash to the current algorithm and parameters.
+// Failures are only logged, since the user has already been authenticated.
+func (h *authHandler) rehashPassword(context context.Context, account *entities.Account, password string) {
+	hashedPassword, err := h.cryptography.EncryptPassword(password)
+	if err != nil {
+		logs.ErrorContext(context, "AuthHandler.SignIn: Failed to rehash password", err)
+		return
+	}
+
+	if err := h.repository.UpdatePassword(context, account.ID, hashedPassword); err != nil {
+		logs.ErrorContext(context, "AuthHandler.SignIn: Failed to store rehashed password", err)
+		return
+	}
+
+	account.Password = hashedPassword
+}
+
 // newContext creates a new context with a timeout of 5 seconds for database and external calls.
-func (h *authHandler) newContext() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), 5*time.Second)
+func (h *authHandler) newContext(ctx *fiber.Ctx) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx.UserContext(), 5*time.Second)
 }
 
 // NewAuthHandler initializes a new instance of authHandler and sets up the auth routes.
-func NewAuthHandler(router fiber.Router, repository repositories.AccountRepository, tokenization services.Tokenization, cryptography services.Cryptography) AuthHandler {
+func NewAuthHandler(router fiber.Router, repository repositories.AccountRepository, tokenization services.Tokenization, cryptography services.Cryptography, accountTokens services.AccountTokens, accountMails services.AccountMails, signInGuard services.SignInGuard, jobs services.Jobs) (AuthHandler, error) {
+	decoyHash, err := cryptography.EncryptPassword(uuid.NewString())
+	if err != nil {
+		// Without the decoy, unknown emails would fail differently from wrong passwords
+		logs.Error("AuthHandler: Failed to hash decoy password", err)
+		return nil, err
+	}
+
 	handler := &authHandler{
-		repository:   repository,
-		tokenization: tokenization,
-		cryptography: cryptography,
+		repository:    repository,
+		tokenization:  tokenization,
+		cryptography:  cryptography,
+		accountTokens: accountTokens,
+		accountMails:  accountMails,
+		signInGuard:   signInGuard,
+		jobs:          jobs,
+		decoyHash:     decoyHash,
 	}
 
 	authRoutes := router.Group("/api/auth")
@@ -174,6 +669,20 @@ func NewAuthHandler(router fiber.Router, repository repositories.AccountReposito
 	authRoutes.Post("/signin", handler.SignIn)
 	authRoutes.Post("/signup", handler.SignUp)
 	authRoutes.Post("/refresh", handler.Refresh)
+	authRoutes.Post("/verify-email", handler.VerifyEmail)
+	authRoutes.Post("/email-change/confirm", handler.ConfirmEmailChange)
+	authRoutes.Post("/password/forgot", handler.ForgotPassword)
+	authRoutes.Post("/password/reset", handler.ResetPassword)
+	authRoutes.Post("/magic-link", handler.RequestMagicLink)
+	authRoutes.Post("/magic-link/verify", handler.MagicLinkSignIn)
+
+	requireAuth := middlewares.Auth(tokenization, nil)
+
+	authRoutes.Post("/logout", requireAuth, handler.Logout)
+	authRoutes.Post("/logout-all", requireAuth, handler.LogoutAll)
+	authRoutes.Get("/sessions", requireAuth, handler.Sessions)
+	authRoutes.Delete("/sessions/:id", requireAuth, handler.RevokeSession)
+	authRoutes.Post("/verify-email/resend", requireAuth, handler.ResendVerification)
 
-	return handler
+	return handler, nil
 }
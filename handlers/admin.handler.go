@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"ticket-booking/configs/errs"
+	"ticket-booking/configs/logs"
+	"ticket-booking/dtos/requests"
+	"ticket-booking/dtos/responses"
+	"ticket-booking/entities"
+	"ticket-booking/middlewares"
+	"ticket-booking/repositories"
+	"ticket-booking/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// AdminHandler defines methods for handling admin routes.
+type AdminHandler interface {
+	FindAllAccounts(ctx *fiber.Ctx) error
+	UpdateRoles(ctx *fiber.Ctx) error
+	Unlock(ctx *fiber.Ctx) error
+	GetLogLevel(ctx *fiber.Ctx) error
+	SetLogLevel(ctx *fiber.Ctx) error
+}
+
+// adminHandler is an implementation of AdminHandler that manages accounts on behalf of admins.
+type adminHandler struct {
+	accountRepo repositories.AccountRepository
+}
+
+// newContext creates a new context with a timeout of 5 seconds for database calls.
+func (h *adminHandler) newContext(ctx *fiber.Ctx) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx.UserContext(), 5*time.Second)
+}
+
+// FindAllAccounts retrieves all accounts with their roles, or only the locked ones with ?locked=true.
+func (h *adminHandler) FindAllAccounts(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	var accounts []*entities.Account
+	var err error
+	if ctx.QueryBool("locked") {
+		accounts, err = h.accountRepo.FindLocked(context)
+	} else {
+		accounts, err = h.accountRepo.FindAll(context)
+	}
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AdminHandler.FindAllAccounts: Failed to retrieve accounts", err)
+		return errs.NewInternalServerError(ctx, "Failed to retrieve accounts")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(responses.NewAccountResponse(
+		fiber.StatusOK,
+		"Accounts retrieved successfully",
+		accounts,
+	))
+}
+
+// UpdateRoles replaces the roles of an account.
+func (h *adminHandler) UpdateRoles(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	id, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AdminHandler.UpdateRoles: Invalid ID parameter", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	var request requests.RoleRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AdminHandler.UpdateRoles: Failed to parse request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if err := request.Validate(); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AdminHandler.UpdateRoles: Invalid request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	account, err := h.accountRepo.FindByID(context, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errs.NewNotFound(ctx, "Account not found")
+		}
+		logs.ErrorContext(ctx.UserContext(), "AdminHandler.UpdateRoles: Failed to retrieve account by ID", err)
+		return errs.NewInternalServerError(ctx, "Failed to retrieve account")
+	}
+
+	if err := h.accountRepo.UpdateRoles(context, account.ID, request.Roles); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AdminHandler.UpdateRoles: Failed to update roles", err)
+		return errs.NewInternalServerError(ctx, "Failed to update roles")
+	}
+
+	account.Roles = request.Roles
+
+	return ctx.Status(fiber.StatusOK).JSON(responses.NewAccountResponse(
+		fiber.StatusOK,
+		"Roles updated successfully",
+		[]*entities.Account{account},
+	))
+}
+
+// Unlock lifts a sign-in lockout and clears the failed attempts of an account.
+func (h *adminHandler) Unlock(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	id, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AdminHandler.Unlock: Invalid ID parameter", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	account, err := h.accountRepo.FindByID(context, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errs.NewNotFound(ctx, "Account not found")
+		}
+		logs.ErrorContext(ctx.UserContext(), "AdminHandler.Unlock: Failed to retrieve account by ID", err)
+		return errs.NewInternalServerError(ctx, "Failed to retrieve account")
+	}
+
+	if err := h.accountRepo.ResetFailedSignIns(context, account.ID); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AdminHandler.Unlock: Failed to unlock account", err)
+		return errs.NewInternalServerError(ctx, "Failed to unlock account")
+	}
+
+	account.FailedSignIns = 0
+	account.LockedUntil = nil
+
+	return ctx.Status(fiber.StatusOK).JSON(responses.NewAccountResponse(
+		fiber.StatusOK,
+		"Account unlocked successfully",
+		[]*entities.Account{account},
+	))
+}
+
+// GetLogLevel returns the current log level.
+func (h *adminHandler) GetLogLevel(ctx *fiber.Ctx) error {
+	return ctx.Status(fiber.StatusOK).JSON(responses.NewLogLevelResponse(
+		fiber.StatusOK,
+		"Log level retrieved successfully",
+		logs.LogLevel(),
+	))
+}
+
+// SetLogLevel changes the log level of the running process, e.g. to debug an incident without a
+// restart. It is not persisted: a restart goes back to the configured level.
+func (h *adminHandler) SetLogLevel(ctx *fiber.Ctx) error {
+	var request requests.LogLevelRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AdminHandler.SetLogLevel: Failed to parse request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if err := request.Validate(); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AdminHandler.SetLogLevel: Invalid request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	previous := logs.LogLevel()
+	if err := logs.SetLogLevel(request.Level); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "AdminHandler.SetLogLevel: Failed to set log level", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+	logs.WarnContext(ctx.UserContext(), "Log level changed", zap.String("from", previous), zap.String("to", logs.LogLevel()),
+		zap.String("by", middlewares.GetPrincipal(ctx).AccountID.String()))
+
+	return ctx.Status(fiber.StatusOK).JSON(responses.NewLogLevelResponse(
+		fiber.StatusOK,
+		"Log level updated successfully",
+		logs.LogLevel(),
+	))
+}
+
+// NewAdminHandler creates a new instance of AdminHandler and sets up the admin routes.
+func NewAdminHandler(router fiber.Router, accountRepo repositories.AccountRepository, tokenization services.Tokenization) AdminHandler {
+	handler := &adminHandler{
+		accountRepo: accountRepo,
+	}
+
+	adminRoutes := router.Group("/api/admin")
+
+	adminRoutes.Use(middlewares.Logger())
+	adminRoutes.Use(middlewares.Auth(tokenization, nil))
+	adminRoutes.Use(middlewares.RequireRole(entities.RoleAdmin))
+
+	adminRoutes.Get("/accounts", handler.FindAllAccounts)       // Retrieve all accounts
+	adminRoutes.Put("/accounts/:id/roles", handler.UpdateRoles) // Assign roles to an account
+	adminRoutes.Post("/accounts/:id/unlock", handler.Unlock)    // Lift a sign-in lockout
+	adminRoutes.Get("/log-level", handler.GetLogLevel)          // Retrieve the current log level
+	adminRoutes.Put("/log-level", handler.SetLogLevel)          // Change the log level until the next restart
+
+	return handler
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"ticket-booking/configs/errs"
+	"ticket-booking/configs/logs"
+	"ticket-booking/dtos/requests"
+	"ticket-booking/dtos/responses"
+	"ticket-booking/entities"
+	"ticket-booking/middlewares"
+	"ticket-booking/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// APIKeyHandler defines methods for managing the caller's API keys.
+type APIKeyHandler interface {
+	FindAll(ctx *fiber.Ctx) error
+	Create(ctx *fiber.Ctx) error
+	Revoke(ctx *fiber.Ctx) error
+}
+
+// apiKeyHandler is an implementation of APIKeyHandler backed by the API key service.
+type apiKeyHandler struct {
+	apiKeys services.APIKeys
+}
+
+// newContext creates a new context with a timeout of 5 seconds for database calls.
+func (h *apiKeyHandler) newContext(ctx *fiber.Ctx) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx.UserContext(), 5*time.Second)
+}
+
+// FindAll lists the caller's active API keys, without their secret part.
+func (h *apiKeyHandler) FindAll(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	principal := middlewares.GetPrincipal(ctx)
+
+	keys, err := h.apiKeys.FindAll(context, principal.AccountID)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "APIKeyHandler.FindAll: Failed to retrieve API keys", err)
+		return errs.NewInternalServerError(ctx, "Failed to retrieve API keys")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewAPIKeyResponse(
+			fiber.StatusOK,
+			"API keys retrieved successfully",
+			keys,
+		))
+}
+
+// Create issues a new API key. Its scopes must be permissions the caller holds.
+func (h *apiKeyHandler) Create(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	principal := middlewares.GetPrincipal(ctx)
+
+	var request requests.APIKeyRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "APIKeyHandler.Create: Failed to parse request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if err := request.Validate(); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "APIKeyHandler.Create: Invalid request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	for _, scope := range request.Scopes {
+		if !principal.HasScope(entities.Permission(scope)) {
+			logs.WarnContext(ctx.UserContext(), "APIKeyHandler.Create: Scope not granted to the caller")
+			return errs.NewForbidden(ctx, "Cannot grant a scope you do not have")
+		}
+	}
+
+	key, model, err := h.apiKeys.Create(context, principal.AccountID, request.Name, request.Scopes, request.ExpiresAt)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "APIKeyHandler.Create: Failed to create API key", err)
+		return errs.NewInternalServerError(ctx, "Failed to create API key")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(
+		responses.NewCreatedAPIKeyResponse(
+			fiber.StatusCreated,
+			"API key created, store it now as it will not be shown again",
+			key,
+			model,
+		))
+}
+
+// Revoke revokes one of the caller's API keys.
+func (h *apiKeyHandler) Revoke(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	principal := middlewares.GetPrincipal(ctx)
+
+	id, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "APIKeyHandler.Revoke: Invalid ID parameter", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	revoked, err := h.apiKeys.Revoke(context, principal.AccountID, id)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "APIKeyHandler.Revoke: Failed to revoke API key", err)
+		return errs.NewInternalServerError(ctx, "Failed to revoke API key")
+	}
+
+	if !revoked {
+		return errs.NewNotFound(ctx, "API key not found")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewBaseResponse(
+			fiber.StatusOK,
+			"API key revoked successfully",
+		))
+}
+
+// NewAPIKeyHandler creates a new instance of APIKeyHandler and sets up the API key routes.
+// Keys can only be managed from a signed-in session, never with another key.
+func NewAPIKeyHandler(router fiber.Router, apiKeys services.APIKeys, tokenization services.Tokenization) APIKeyHandler {
+	handler := &apiKeyHandler{
+		apiKeys: apiKeys,
+	}
+
+	apiKeyRoutes := router.Group("/api/api-keys")
+
+	apiKeyRoutes.Use(middlewares.Logger())
+	apiKeyRoutes.Use(middlewares.Auth(tokenization, nil))
+
+	apiKeyRoutes.Get("/", handler.FindAll)
+	apiKeyRoutes.Post("/", handler.Create)
+	apiKeyRoutes.Delete("/:id", handler.Revoke)
+
+	return handler
+}
@@ -3,7 +3,6 @@ package handlers
 import (
 	"context"
 	"database/sql"
-	"strconv"
 	"ticket-booking/configs/errs"
 	"ticket-booking/configs/logs"
 	"ticket-booking/dtos/requests"
@@ -35,13 +34,13 @@ type eventHandler struct {
 }
 
 // NewContext creates a new context with a timeout of 5 seconds.
-func (h *eventHandler) newContext() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), 5*time.Second)
+func (h *eventHandler) newContext(ctx *fiber.Ctx) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx.UserContext(), 5*time.Second)
 }
 
 // FindAll retrieves all events.
 func (h *eventHandler) FindAll(ctx *fiber.Ctx) error {
-	context, cancel := h.newContext()
+	context, cancel := h.newContext(ctx)
 	defer cancel()
 
 	events, err := h.repository.FindAll(context)
@@ -50,7 +49,7 @@ func (h *eventHandler) FindAll(ctx *fiber.Ctx) error {
 			return errs.NewNotFound(ctx, "No events found")
 		}
 
-		logs.Error("EventHandler.FindAll: Failed to retrieve events", err)
+		logs.ErrorContext(ctx.UserContext(), "EventHandler.FindAll: Failed to retrieve events", err)
 		return errs.NewInternalServerError(ctx, "Failed to retrieve events")
 	}
 
@@ -67,21 +66,21 @@ func (h *eventHandler) FindAll(ctx *fiber.Ctx) error {
 
 // FindByID retrieves an event by its ID.
 func (h *eventHandler) FindByID(ctx *fiber.Ctx) error {
-	context, cancel := h.newContext()
+	context, cancel := h.newContext(ctx)
 	defer cancel()
 
-	id, err := strconv.ParseUint(ctx.Params("id"), 10, 64) // or 32 if it's a smaller range
+	id, err := entities.ParsePublicID(ctx.Params("id"))
 	if err != nil {
-		logs.Error("EventHandler.FindByID: Invalid ID parameter", err)
+		logs.ErrorContext(ctx.UserContext(), "EventHandler.FindByID: Invalid ID parameter", err)
 		return errs.NewBadRequest(ctx, "Invalid parameter")
 	}
 
-	event, err := h.repository.FindByID(context, id)
+	event, err := h.repository.FindByPublicID(context, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return errs.NewNotFound(ctx, "Event not found")
 		}
-		logs.Error("EventHandler.FindByID: Failed to retrieve event by ID", err)
+		logs.ErrorContext(ctx.UserContext(), "EventHandler.FindByID: Failed to retrieve event by ID", err)
 		return errs.NewInternalServerError(ctx, "Failed to retrieve events")
 	}
 
@@ -94,25 +93,25 @@ func (h *eventHandler) FindByID(ctx *fiber.Ctx) error {
 
 // Create creates a new event.
 func (h *eventHandler) Create(ctx *fiber.Ctx) error {
-	context, cancel := h.newContext()
+	context, cancel := h.newContext(ctx)
 	defer cancel()
 
 	var request requests.EventRequest
 	if err := ctx.BodyParser(&request); err != nil {
-		logs.Error("EventHandler.Create: Failed to parse request body", err)
+		logs.ErrorContext(ctx.UserContext(), "EventHandler.Create: Failed to parse request body", err)
 		return errs.NewBadRequest(ctx, "Invalid parameter")
 	}
 
 	// Validate the request
 	if err := request.Validate(); err != nil {
-		logs.Error("EventHandler.Create: Failed to parse request body", err)
+		logs.ErrorContext(ctx.UserContext(), "EventHandler.Create: Failed to parse request body", err)
 		return errs.NewBadRequest(ctx, "Invalid parameter")
 	}
 
 	newEvent := entities.NewEvent(request.Title, request.Location, request.Date)
 	err := h.repository.Create(context, newEvent)
 	if err != nil {
-		logs.Error("EventHandler.Create: Failed to create event", err)
+		logs.ErrorContext(ctx.UserContext(), "EventHandler.Create: Failed to create event", err)
 		return errs.NewInternalServerError(ctx, "Failed to create event")
 	}
 
@@ -125,27 +124,27 @@ func (h *eventHandler) Create(ctx *fiber.Ctx) error {
 
 // Update updates an event by its ID.
 func (h *eventHandler) Update(ctx *fiber.Ctx) error {
-	context, cancel := h.newContext()
+	context, cancel := h.newContext(ctx)
 	defer cancel()
 
-	id, err := strconv.ParseUint(ctx.Params("id"), 10, 64) // or 32 if it's a smaller range
+	id, err := entities.ParsePublicID(ctx.Params("id"))
 	if err != nil {
-		logs.Error("EventHandler.FindByID: Invalid ID parameter", err)
+		logs.ErrorContext(ctx.UserContext(), "EventHandler.Update: Invalid ID parameter", err)
 		return errs.NewBadRequest(ctx, "Invalid parameter")
 	}
 
 	var request requests.EventRequest
 	if err := ctx.BodyParser(&request); err != nil {
-		logs.Error("EventHandler.Update: Failed to parse request body", err)
+		logs.ErrorContext(ctx.UserContext(), "EventHandler.Update: Failed to parse request body", err)
 		return errs.NewBadRequest(ctx, "Invalid parameter")
 	}
 
-	event, err := h.repository.FindByID(context, id)
+	event, err := h.repository.FindByPublicID(context, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return errs.NewNotFound(ctx, "Event not found")
 		}
-		logs.Error("EventHandler.Update: Failed to retrieve event by ID", err)
+		logs.ErrorContext(ctx.UserContext(), "EventHandler.Update: Failed to retrieve event by ID", err)
 		return errs.NewInternalServerError(ctx, "Failed to retrieve events")
 	}
 
@@ -163,7 +162,7 @@ func (h *eventHandler) Update(ctx *fiber.Ctx) error {
 
 	err = h.repository.Update(context, event)
 	if err != nil {
-		logs.Error("EventHandler.Update: Failed to update event", err)
+		logs.ErrorContext(ctx.UserContext(), "EventHandler.Update: Failed to update event", err)
 		return errs.NewInternalServerError(ctx, "Failed to update event")
 	}
 
@@ -176,27 +175,27 @@ func (h *eventHandler) Update(ctx *fiber.Ctx) error {
 
 // Delete deletes an event by its ID.
 func (h *eventHandler) Delete(ctx *fiber.Ctx) error {
-	context, cancel := h.newContext()
+	context, cancel := h.newContext(ctx)
 	defer cancel()
 
-	id, err := strconv.ParseUint(ctx.Params("id"), 10, 64) // or 32 if it's a smaller range
+	id, err := entities.ParsePublicID(ctx.Params("id"))
 	if err != nil {
-		logs.Error("EventHandler.FindByID: Invalid ID parameter", err)
+		logs.ErrorContext(ctx.UserContext(), "EventHandler.Delete: Invalid ID parameter", err)
 		return errs.NewBadRequest(ctx, "Invalid parameter")
 	}
 
-	event, err := h.repository.FindByID(context, id)
+	event, err := h.repository.FindByPublicID(context, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return errs.NewNotFound(ctx, "Event not found")
 		}
-		logs.Error("EventHandler.Delete: Failed to retrieve event by ID", err)
+		logs.ErrorContext(ctx.UserContext(), "EventHandler.Delete: Failed to retrieve event by ID", err)
 		return errs.NewInternalServerError(ctx, "Failed to retrieve events")
 	}
 
 	err = h.repository.Delete(context, event.ID)
 	if err != nil {
-		logs.Error("EventHandler.Delete: Failed to delete event", err)
+		logs.ErrorContext(ctx.UserContext(), "EventHandler.Delete: Failed to delete event", err)
 		return errs.NewInternalServerError(ctx, "Failed to delete event")
 	}
 
@@ -208,7 +207,7 @@ func (h *eventHandler) Delete(ctx *fiber.Ctx) error {
 }
 
 // NewEventHandler creates a new instance of EventHandler and sets up the event routes.
-func NewEventHandler(router fiber.Router, repository repositories.EventRepository, tokenization services.Tokenization) EventHandler {
+func NewEventHandler(router fiber.Router, repository repositories.EventRepository, tokenization services.Tokenization, apiKeys services.APIKeys) EventHandler {
 	handler := &eventHandler{
 		repository: repository,
 	}
@@ -216,13 +215,16 @@ func NewEventHandler(router fiber.Router, repository repositories.EventRepositor
 	eventRoutes := router.Group("/api/events")
 
 	eventRoutes.Use(middlewares.Logger())
-	eventRoutes.Use(middlewares.Auth(tokenization))
+	eventRoutes.Use(middlewares.Auth(tokenization, apiKeys))
 
-	eventRoutes.Get("/", handler.FindAll)      // Retrieve all events
-	eventRoutes.Post("/", handler.Create)      // Create a new event
-	eventRoutes.Get("/:id", handler.FindByID)  // Retrieve an event by ID
-	eventRoutes.Put("/:id", handler.Update)    // Update an event by ID
-	eventRoutes.Delete("/:id", handler.Delete) // Delete an event by ID
+	canRead := middlewares.RequirePermission(entities.PermissionEventsRead)
+	canWrite := middlewares.RequirePermission(entities.PermissionEventsWrite)
+
+	eventRoutes.Get("/", canRead, handler.FindAll)       // Retrieve all events
+	eventRoutes.Post("/", canWrite, handler.Create)      // Create a new event
+	eventRoutes.Get("/:id", canRead, handler.FindByID)   // Retrieve an event by ID
+	eventRoutes.Put("/:id", canWrite, handler.Update)    // Update an event by ID
+	eventRoutes.Delete("/:id", canWrite, handler.Delete) // Delete an event by ID
 
 	return handler
 }
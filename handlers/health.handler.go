@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"ticket-booking/dtos/responses"
+	"ticket-booking/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HealthHandler defines the probes orchestrators use to decide whether to restart the process
+// and whether to route traffic to it.
+type HealthHandler interface {
+	Live(ctx *fiber.Ctx) error
+	Ready(ctx *fiber.Ctx) error
+}
+
+// healthHandler is an implementation of HealthHandler backed by the health service.
+type healthHandler struct {
+	health services.Health
+}
+
+// newContext creates a new context with a timeout of 2 seconds, well below usual probe timeouts.
+func (h *healthHandler) newContext(ctx *fiber.Ctx) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx.UserContext(), 2*time.Second)
+}
+
+// Live reports that the process is up and serving requests; it checks no dependency, so a
+// database outage does not get the process restarted.
+func (h *healthHandler) Live(ctx *fiber.Ctx) error {
+	return ctx.Status(fiber.StatusOK).JSON(&responses.HealthResponse{Status: services.HealthUp})
+}
+
+// Ready reports whether the process can serve traffic, with the outcome and latency of each check.
+func (h *healthHandler) Ready(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	response := h.health.Ready(context)
+
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	if response.Status != services.HealthUp {
+		return ctx.Status(fiber.StatusServiceUnavailable).JSON(response)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+// NewHealthHandler creates a new instance of HealthHandler and sets up the probe routes. They are not
+// logged, as orchestrators call them every few seconds.
+func NewHealthHandler(router fiber.Router, health services.Health) HealthHandler {
+	handler := &healthHandler{
+		health: health,
+	}
+
+	router.Get("/healthz", handler.Live)
+	router.Get("/readyz", handler.Ready)
+
+	return handler
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"ticket-booking/middlewares"
+	"ticket-booking/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// JWKSHandler defines methods for publishing token verification keys.
+type JWKSHandler interface {
+	Keys(ctx *fiber.Ctx) error
+}
+
+// jwksHandler is an implementation of JWKSHandler backed by the token service.
+type jwksHandler struct {
+	tokenization services.Tokenization
+}
+
+// Keys returns the public keys scanner devices and partner services verify tokens with.
+func (h *jwksHandler) Keys(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return ctx.Status(fiber.StatusOK).JSON(h.tokenization.JWKS())
+}
+
+// NewJWKSHandler creates a new instance of JWKSHandler and sets up the well-known route.
+func NewJWKSHandler(router fiber.Router, tokenization services.Tokenization) JWKSHandler {
+	handler := &jwksHandler{
+		tokenization: tokenization,
+	}
+
+	router.Get("/.well-known/jwks.json", middlewares.Logger(), handler.Keys)
+
+	return handler
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"ticket-booking/configs/metrics"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+)
+
+// MetricsHandler defines the endpoint Prometheus scrapes.
+type MetricsHandler interface {
+	Metrics(ctx *fiber.Ctx) error
+}
+
+// metricsHandler is an implementation of MetricsHandler serving the application registry.
+type metricsHandler struct {
+	handler fiber.Handler
+}
+
+// Metrics returns the current value of every metric in the Prometheus text format.
+func (h *metricsHandler) Metrics(ctx *fiber.Ctx) error {
+	return h.handler(ctx)
+}
+
+// NewMetricsHandler creates a new instance of MetricsHandler and sets up the metrics route. It is not
+// logged, as Prometheus scrapes it every few seconds.
+func NewMetricsHandler(router fiber.Router) MetricsHandler {
+	handler := &metricsHandler{
+		handler: adaptor.HTTPHandler(metrics.Handler()),
+	}
+
+	router.Get("/metrics", handler.Metrics)
+
+	return handler
+}
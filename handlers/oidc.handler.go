@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"ticket-booking/configs/errs"
+	"ticket-booking/configs/logs"
+	"ticket-booking/dtos/requests"
+	"ticket-booking/entities"
+	"ticket-booking/middlewares"
+	"ticket-booking/repositories"
+	"ticket-booking/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// OIDCHandler defines methods for signing in with an external identity provider.
+type OIDCHandler interface {
+	Authorize(ctx *fiber.Ctx) error
+	Callback(ctx *fiber.Ctx) error
+}
+
+// oidcHandler is an implementation of OIDCHandler that links provider identities to accounts.
+type oidcHandler struct {
+	accountRepo  repositories.AccountRepository
+	oidcRepo     repositories.OIDCRepository
+	oidc         services.OIDC
+	tokenization services.Tokenization
+	cryptography services.Cryptography
+}
+
+// newContext creates a new context with a timeout of 10 seconds, leaving room for the calls to the provider.
+func (h *oidcHandler) newContext(ctx *fiber.Ctx) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx.UserContext(), 10*time.Second)
+}
+
+// oidcStateCookie binds a sign-in to the browser that started it: the callback is only accepted with
+// the cookie holding the hash of its state, so an attacker cannot have a victim's browser complete a
+// sign-in the attacker started and end up in the attacker's account.
+const oidcStateCookie = "oidc_state"
+
+// Authorize redirects the browser to the provider's sign-in page.
+func (h *oidcHandler) Authorize(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	url, state, err := h.oidc.AuthorizationURL(context, ctx.Params("provider"))
+	if err != nil {
+		if errors.Is(err, services.ErrOIDCProviderUnknown) {
+			return errs.NewNotFound(ctx, "Identity provider not found")
+		}
+		logs.ErrorContext(ctx.UserContext(), "OIDCHandler.Authorize: Failed to start authorization", err)
+		return errs.NewInternalServerError(ctx, "Failed to sign in")
+	}
+
+	setStateCookie(ctx, hashState(state), time.Now().Add(services.OIDCStateExpiry))
+
+	return ctx.Redirect(url, fiber.StatusFound)
+}
+
+// setStateCookie stores the hashed state until expires. Lax still sends it on the provider's
+// top-level redirect back to the callback.
+func setStateCookie(ctx *fiber.Ctx, value string, expires time.Time) {
+	ctx.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     "/api/auth/oidc",
+		Expires:  expires,
+		Secure:   ctx.Protocol() == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// hashState keeps the state itself out of the cookie.
+func hashState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Callback completes the sign-in. The account is found through a previously linked identity,
+// linked by verified email, or created on first sign-in. The session is issued like a password sign-in.
+func (h *oidcHandler) Callback(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	if providerError := ctx.Query("error"); providerError != "" {
+		logs.WarnContext(ctx.UserContext(), "OIDCHandler.Callback: Sign-in refused by the provider")
+		return errs.NewUnauthorized(ctx, "Sign-in was cancelled or refused by the identity provider")
+	}
+
+	var request requests.OIDCCallbackRequest
+	parse := ctx.QueryParser
+	if ctx.Method() == fiber.MethodPost {
+		parse = ctx.BodyParser
+	}
+	if err := parse(&request); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "OIDCHandler.Callback: Failed to parse request", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if err := request.Validate(); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "OIDCHandler.Callback: Invalid request", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	bound := subtle.ConstantTimeCompare([]byte(ctx.Cookies(oidcStateCookie)), []byte(hashState(request.State))) == 1
+	setStateCookie(ctx, "", time.Unix(0, 0))
+	if !bound {
+		logs.WarnContext(ctx.UserContext(), "OIDCHandler.Callback: State not bound to this browser")
+		return errs.NewUnauthorized(ctx, "Invalid or expired sign-in attempt")
+	}
+
+	identity, err := h.oidc.Exchange(context, ctx.Params("provider"), request.Code, request.State)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "OIDCHandler.Callback: Failed to complete authorization", err)
+		switch {
+		case errors.Is(err, services.ErrOIDCProviderUnknown):
+			return errs.NewNotFound(ctx, "Identity provider not found")
+		case errors.Is(err, services.ErrOIDCStateInvalid), errors.Is(err, services.ErrOIDCTokenInvalid):
+			return errs.NewUnauthorized(ctx, "Invalid or expired sign-in attempt")
+		}
+		return errs.NewInternalServerError(ctx, "Failed to sign in")
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		logs.WarnContext(ctx.UserContext(), "OIDCHandler.Callback: Provider did not assert a verified email")
+		return errs.NewUnauthorized(ctx, "The identity provider did not confirm your email address")
+	}
+
+	account, err := h.resolveAccount(context, identity)
+	if err != nil {
+		if errors.Is(err, errUnverifiedAccount) {
+			return errs.NewBadRequest(ctx, "An account with this email exists, verify its email address before signing in with this provider")
+		}
+		logs.ErrorContext(ctx.UserContext(), "OIDCHandler.Callback: Failed to resolve account", err)
+		return errs.NewInternalServerError(ctx, "Failed to sign in")
+	}
+
+	return issueSession(context, ctx, h.tokenization, account, "OIDCHandler.Callback")
+}
+
+// errUnverifiedAccount refuses to link a provider to an account whose email was never verified:
+// whoever registered it may not own the address, and would keep access through the password.
+var errUnverifiedAccount = errors.New("existing account email is not verified")
+
+// resolveAccount returns the account of the identity, linking or creating it as needed.
+func (h *oidcHandler) resolveAccount(context context.Context, identity *services.OIDCIdentity) (*entities.Account, error) {
+	link, err := h.oidcRepo.FindIdentity(context, identity.Provider, identity.Subject)
+	if err == nil {
+		return h.accountRepo.FindByID(context, link.AccountID)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	account, err := h.accountRepo.FindByEmail(context, identity.Email)
+	switch {
+	case err == nil:
+		if !account.IsEmailVerified() {
+			return nil, errUnverifiedAccount
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		if account, err = h.createAccount(context, identity); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if err := h.oidcRepo.CreateIdentity(context, entities.NewAccountIdentity(account.ID, identity.Provider, identity.Subject, identity.Email)); err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// createAccount registers an account for a first sign-in. Its password is random and never revealed;
+// the user can set one through the password reset flow.
+func (h *oidcHandler) createAccount(context context.Context, identity *services.OIDCIdentity) (*entities.Account, error) {
+	name := identity.Name
+	if len(name) < 3 {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	hashedPassword, err := h.cryptography.EncryptPassword(uuid.NewString())
+	if err != nil {
+		return nil, err
+	}
+
+	account := entities.NewAccount(name, identity.Email, hashedPassword)
+	if err := h.accountRepo.SignUp(context, account); err != nil {
+		return nil, err
+	}
+
+	if err := h.accountRepo.MarkEmailVerified(context, account.ID); err != nil {
+		return nil, err
+	}
+	account.EmailVerifiedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	return account, nil
+}
+
+// NewOIDCHandler creates a new instance of OIDCHandler and sets up the /api/auth/oidc routes.
+func NewOIDCHandler(router fiber.Router, accountRepo repositories.AccountRepository, oidcRepo repositories.OIDCRepository, oidc services.OIDC, tokenization services.Tokenization, cryptography services.Cryptography) OIDCHandler {
+	handler := &oidcHandler{
+		accountRepo:  accountRepo,
+		oidcRepo:     oidcRepo,
+		oidc:         oidc,
+		tokenization: tokenization,
+		cryptography: cryptography,
+	}
+
+	oidcRoutes := router.Group("/api/auth/oidc")
+	oidcRoutes.Use(middlewares.Logger())
+
+	oidcRoutes.Get("/:provider", handler.Authorize)
+	oidcRoutes.Get("/:provider/callback", handler.Callback)
+	oidcRoutes.Post("/:provider/callback", handler.Callback)
+
+	return handler
+}
@@ -0,0 +1,357 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"ticket-booking/dtos/responses"
+	"ticket-booking/entities"
+	"ticket-booking/repositories"
+	"ticket-booking/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// fakeOIDC asserts a fixed identity for any code and state.
+type fakeOIDC struct {
+	identity  services.OIDCIdentity
+	exchanges int
+}
+
+func (o *fakeOIDC) AuthorizationURL(context.Context, string) (string, string, error) {
+	return "https://idp.example.com/authorize?state=state", "state", nil
+}
+
+func (o *fakeOIDC) Exchange(_ context.Context, provider, _, _ string) (*services.OIDCIdentity, error) {
+	o.exchanges++
+	if provider != o.identity.Provider {
+		return nil, services.ErrOIDCProviderUnknown
+	}
+
+	identity := o.identity
+	return &identity, nil
+}
+
+// fakeAccountRepository keeps accounts in memory. Only the methods used by the tested handlers are implemented.
+type fakeAccountRepository struct {
+	repositories.AccountRepository
+	mu       sync.Mutex
+	accounts map[uuid.UUID]*entities.Account
+}
+
+func newFakeAccountRepository(accounts ...*entities.Account) *fakeAccountRepository {
+	repository := &fakeAccountRepository{accounts: make(map[uuid.UUID]*entities.Account)}
+	for _, account := range accounts {
+		repository.accounts[account.ID] = account
+	}
+
+	return repository
+}
+
+func (r *fakeAccountRepository) SignUp(_ context.Context, account *entities.Account) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.accounts[account.ID] = account
+	return nil
+}
+
+func (r *fakeAccountRepository) FindByEmail(_ context.Context, email string) (*entities.Account, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, account := range r.accounts {
+		if account.Email == entities.NormalizeEmail(email) {
+			return account, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeAccountRepository) FindByID(_ context.Context, id uuid.UUID) (*entities.Account, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return account, nil
+}
+
+func (r *fakeAccountRepository) MarkEmailVerified(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.accounts[id].EmailVerifiedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (r *fakeAccountRepository) UpdateProfile(_ context.Context, account *entities.Account) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.accounts[account.ID].Name = account.Name
+	return nil
+}
+
+func (r *fakeAccountRepository) SetPendingEmail(_ context.Context, id uuid.UUID, email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.accounts[id].PendingEmail = sql.NullString{String: email, Valid: true}
+	return nil
+}
+
+// fakeIdentityRepository keeps the linked identities in memory.
+type fakeIdentityRepository struct {
+	repositories.OIDCRepository
+	mu         sync.Mutex
+	identities []*entities.AccountIdentity
+}
+
+func (r *fakeIdentityRepository) FindIdentity(_ context.Context, provider, subject string) (*entities.AccountIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeIdentityRepository) CreateIdentity(_ context.Context, identity *entities.AccountIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.identities = append(r.identities, identity)
+	return nil
+}
+
+// fakeTokenization issues opaque tokens, and accepts an account ID as access token. Only the methods
+// used by the tested handlers are implemented.
+type fakeTokenization struct {
+	services.Tokenization
+}
+
+func (t *fakeTokenization) ParseToken(token string) (*entities.Principal, error) {
+	accountID, err := uuid.Parse(token)
+	if err != nil {
+		return nil, err
+	}
+	return &entities.Principal{AccountID: accountID, Roles: entities.DefaultRoles()}, nil
+}
+
+func (t *fakeTokenization) IsRevoked(context.Context, *entities.Principal) (bool, error) {
+	return false, nil
+}
+
+func (t *fakeTokenization) GenerateToken(context.Context, string, []string, string, string) (*responses.TokenResponse, error) {
+	return responses.NewTokenResponse(uuid.NewString(), uuid.NewString(), time.Now().Add(time.Hour)), nil
+}
+
+func (t *fakeTokenization) GenerateChallengeToken(uuid.UUID) (string, time.Time, error) {
+	return uuid.NewString(), time.Now().Add(5 * time.Minute), nil
+}
+
+type fakeCryptography struct {
+	services.Cryptography
+}
+
+func (c *fakeCryptography) EncryptPassword(password string) (string, error) {
+	return "hashed:" + password, nil
+}
+
+func (c *fakeCryptography) VerifyPassword(password, hashedPassword string) (bool, bool, error) {
+	return hashedPassword == "hashed:"+password, false, nil
+}
+
+var providerIdentity = services.OIDCIdentity{
+	Provider:      "mock",
+	Subject:       "subject-1",
+	Email:         "jane.doe@example.com",
+	EmailVerified: true,
+	Name:          "Jane Doe",
+}
+
+// callback sends the provider's redirect to a handler wired with the given repositories.
+func callback(t *testing.T, identity services.OIDCIdentity, accountRepo *fakeAccountRepository, oidcRepo *fakeIdentityRepository) int {
+	t.Helper()
+
+	app := fiber.New()
+	NewOIDCHandler(app, accountRepo, oidcRepo, &fakeOIDC{identity: identity}, &fakeTokenization{}, &fakeCryptography{})
+
+	request := httptest.NewRequest(fiber.MethodGet, "/api/auth/oidc/mock/callback?code=code&state=state", nil)
+	request.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: hashState("state")})
+
+	response, err := app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	return response.StatusCode
+}
+
+func verifiedAccount(email string) *entities.Account {
+	account := entities.NewAccount("Jane", email, "hashed")
+	account.EmailVerifiedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return account
+}
+
+func TestOIDCCallbackRefusesUnverifiedProviderEmail(t *testing.T) {
+	identity := providerIdentity
+	identity.EmailVerified = false
+	accountRepo := newFakeAccountRepository(verifiedAccount("jane.doe@example.com"))
+	oidcRepo := &fakeIdentityRepository{}
+
+	if status := callback(t, identity, accountRepo, oidcRepo); status != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", status, http.StatusUnauthorized)
+	}
+	if len(oidcRepo.identities) != 0 {
+		t.Errorf("an identity was linked without a verified email: %+v", oidcRepo.identities[0])
+	}
+}
+
+func TestOIDCCallbackLinksVerifiedAccount(t *testing.T) {
+	// The account was registered with different casing than the provider asserts
+	account := verifiedAccount("Jane.Doe@Example.com")
+	accountRepo := newFakeAccountRepository(account)
+	oidcRepo := &fakeIdentityRepository{}
+
+	if status := callback(t, providerIdentity, accountRepo, oidcRepo); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	if len(accountRepo.accounts) != 1 {
+		t.Errorf("%d accounts, want the existing one only", len(accountRepo.accounts))
+	}
+	if len(oidcRepo.identities) != 1 || oidcRepo.identities[0].AccountID != account.ID {
+		t.Fatalf("identities = %+v, want one linked to %s", oidcRepo.identities, account.ID)
+	}
+
+	// Later sign-ins find the account through the link
+	if status := callback(t, providerIdentity, accountRepo, oidcRepo); status != http.StatusOK {
+		t.Fatalf("second sign-in status = %d, want %d", status, http.StatusOK)
+	}
+	if len(oidcRepo.identities) != 1 {
+		t.Errorf("%d identities after the second sign-in, want 1", len(oidcRepo.identities))
+	}
+}
+
+func TestOIDCCallbackRefusesUnverifiedAccount(t *testing.T) {
+	account := entities.NewAccount("Jane", "jane.doe@example.com", "hashed")
+	accountRepo := newFakeAccountRepository(account)
+	oidcRepo := &fakeIdentityRepository{}
+
+	if status := callback(t, providerIdentity, accountRepo, oidcRepo); status != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", status, http.StatusBadRequest)
+	}
+	if len(oidcRepo.identities) != 0 {
+		t.Errorf("the provider was linked to an unverified account: %+v", oidcRepo.identities[0])
+	}
+}
+
+func TestOIDCCallbackCreatesAccount(t *testing.T) {
+	accountRepo := newFakeAccountRepository()
+	oidcRepo := &fakeIdentityRepository{}
+
+	if status := callback(t, providerIdentity, accountRepo, oidcRepo); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+
+	account, err := accountRepo.FindByEmail(context.Background(), providerIdentity.Email)
+	if err != nil {
+		t.Fatalf("no account created: %v", err)
+	}
+	if !account.IsEmailVerified() {
+		t.Error("the created account's email is not verified")
+	}
+	if len(oidcRepo.identities) != 1 || oidcRepo.identities[0].AccountID != account.ID {
+		t.Errorf("identities = %+v, want one linked to %s", oidcRepo.identities, account.ID)
+	}
+}
+
+func TestOIDCCallbackChallengesTwoFactorAccounts(t *testing.T) {
+	account := verifiedAccount("jane.doe@example.com")
+	account.TOTPEnabled = true
+	accountRepo := newFakeAccountRepository(account)
+
+	if status := callback(t, providerIdentity, accountRepo, &fakeIdentityRepository{}); status != http.StatusAccepted {
+		t.Errorf("status = %d, want %d", status, http.StatusAccepted)
+	}
+}
+
+func TestOIDCAuthorizeBindsStateToBrowser(t *testing.T) {
+	app := fiber.New()
+	NewOIDCHandler(app, newFakeAccountRepository(), &fakeIdentityRepository{}, &fakeOIDC{identity: providerIdentity}, &fakeTokenization{}, &fakeCryptography{})
+
+	response, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/api/auth/oidc/mock", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusFound {
+		t.Fatalf("status = %d, want %d", response.StatusCode, http.StatusFound)
+	}
+
+	var cookie *http.Cookie
+	for _, c := range response.Cookies() {
+		if c.Name == oidcStateCookie {
+			cookie = c
+		}
+	}
+	if cookie == nil {
+		t.Fatal("no state cookie set")
+	}
+	if cookie.Value != hashState("state") {
+		t.Errorf("cookie value = %q, want the hash of the state", cookie.Value)
+	}
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("cookie HttpOnly = %v, SameSite = %v, want HttpOnly and Lax", cookie.HttpOnly, cookie.SameSite)
+	}
+}
+
+func TestOIDCCallbackRefusesUnboundState(t *testing.T) {
+	tests := []struct {
+		name   string
+		cookie string
+	}{
+		{name: "no cookie"},
+		{name: "another sign-in", cookie: hashState("attacker-state")},
+		{name: "raw state", cookie: "state"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			oidc := &fakeOIDC{identity: providerIdentity}
+			app := fiber.New()
+			NewOIDCHandler(app, newFakeAccountRepository(), &fakeIdentityRepository{}, oidc, &fakeTokenization{}, &fakeCryptography{})
+
+			request := httptest.NewRequest(fiber.MethodGet, "/api/auth/oidc/mock/callback?code=code&state=state", nil)
+			if test.cookie != "" {
+				request.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: test.cookie})
+			}
+
+			response, err := app.Test(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer response.Body.Close()
+
+			if response.StatusCode != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", response.StatusCode, http.StatusUnauthorized)
+			}
+			if oidc.exchanges != 0 {
+				t.Error("the code was exchanged")
+			}
+		})
+	}
+}
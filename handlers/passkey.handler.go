@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ticket-booking/configs/errs"
+	"ticket-booking/configs/logs"
+	"ticket-booking/dtos/requests"
+	"ticket-booking/dtos/responses"
+	"ticket-booking/entities"
+	"ticket-booking/middlewares"
+	"ticket-booking/repositories"
+	"ticket-booking/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// PasskeyHandler defines methods for handling passkey registration and login.
+type PasskeyHandler interface {
+	BeginRegistration(ctx *fiber.Ctx) error
+	FinishRegistration(ctx *fiber.Ctx) error
+	FindAll(ctx *fiber.Ctx) error
+	Delete(ctx *fiber.Ctx) error
+	BeginLogin(ctx *fiber.Ctx) error
+	FinishLogin(ctx *fiber.Ctx) error
+}
+
+// passkeyHandler is an implementation of PasskeyHandler backed by the passkey service.
+type passkeyHandler struct {
+	accountRepo   repositories.AccountRepository
+	twoFactorRepo repositories.TwoFactorRepository
+	passkeys      services.Passkeys
+	tokenization  services.Tokenization
+	cryptography  services.Cryptography
+	totp          services.TOTP
+	signInGuard   services.SignInGuard
+}
+
+// newContext creates a new context with a timeout of 5 seconds for database calls.
+func (h *passkeyHandler) newContext(ctx *fiber.Ctx) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx.UserContext(), 5*time.Second)
+}
+
+// BeginRegistration returns the options for navigator.credentials.create(). Adding a passkey grants a
+// way to sign in, so the caller confirms with the password, and a code when two-factor is enabled.
+func (h *passkeyHandler) BeginRegistration(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	principal := middlewares.GetPrincipal(ctx)
+
+	var request requests.PasskeyBeginRegistrationRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "PasskeyHandler.BeginRegistration: Failed to parse request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if err := request.Validate(); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "PasskeyHandler.BeginRegistration: Invalid request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	account, err := verifyPassword(context, ctx, h.accountRepo, h.cryptography, h.signInGuard, principal, request.Password)
+	if account == nil {
+		return err
+	}
+
+	if account.TOTPEnabled {
+		if request.Code == "" {
+			return errs.NewBadRequest(ctx, "Two-factor code required")
+		}
+
+		verified, err := useTOTPCode(context, h.totp, h.twoFactorRepo, account, request.Code)
+		if err != nil {
+			logs.ErrorContext(ctx.UserContext(), "PasskeyHandler.BeginRegistration: Failed to verify code", err)
+			return errs.NewInternalServerError(ctx, "Failed to register passkey")
+		}
+
+		if !verified {
+			logs.WarnContext(ctx.UserContext(), "PasskeyHandler.BeginRegistration: Invalid code")
+			h.signInGuard.Failed(context, ctx.IP(), account)
+			return errs.NewBadRequest(ctx, "Invalid code")
+		}
+	}
+
+	ceremonyID, options, err := h.passkeys.BeginRegistration(context, account)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "PasskeyHandler.BeginRegistration: Failed to begin registration", err)
+		return errs.NewInternalServerError(ctx, "Failed to register passkey")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewPasskeyCeremonyResponse(
+			fiber.StatusOK,
+			"Passkey registration started",
+			ceremonyID,
+			options,
+		))
+}
+
+// FinishRegistration verifies the new credential and stores it for the caller.
+func (h *passkeyHandler) FinishRegistration(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	principal := middlewares.GetPrincipal(ctx)
+
+	var request requests.PasskeyRegistrationRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "PasskeyHandler.FinishRegistration: Failed to parse request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if err := request.Validate(); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "PasskeyHandler.FinishRegistration: Invalid request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	account, err := h.accountRepo.FindByID(context, principal.AccountID)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "PasskeyHandler.FinishRegistration: Account not found", err)
+		return errs.NewNotFound(ctx, "Account not found")
+	}
+
+	passkey, err := h.passkeys.FinishRegistration(context, account, uuid.MustParse(request.CeremonyID), request.Name, request.Credential)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "PasskeyHandler.FinishRegistration: Failed to register passkey", err)
+		if errors.Is(err, services.ErrPasskeyCeremonyInvalid) || errors.Is(err, services.ErrPasskeyInvalid) {
+			return errs.NewBadRequest(ctx, "Passkey registration failed")
+		}
+		return errs.NewInternalServerError(ctx, "Failed to register passkey")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(
+		responses.NewPasskeyResponse(
+			fiber.StatusCreated,
+			"Passkey registered successfully",
+			[]*entities.Passkey{passkey},
+		))
+}
+
+// FindAll lists the caller's passkeys.
+func (h *passkeyHandler) FindAll(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	principal := middlewares.GetPrincipal(ctx)
+
+	passkeys, err := h.passkeys.FindAll(context, principal.AccountID)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "PasskeyHandler.FindAll: Failed to retrieve passkeys", err)
+		return errs.NewInternalServerError(ctx, "Failed to retrieve passkeys")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewPasskeyResponse(
+			fiber.StatusOK,
+			"Passkeys retrieved successfully",
+			passkeys,
+		))
+}
+
+// Delete removes one of the caller's passkeys.
+func (h *passkeyHandler) Delete(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	principal := middlewares.GetPrincipal(ctx)
+
+	id, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "PasskeyHandler.Delete: Invalid ID parameter", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	deleted, err := h.passkeys.Delete(context, principal.AccountID, id)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "PasskeyHandler.Delete: Failed to delete passkey", err)
+		return errs.NewInternalServerError(ctx, "Failed to delete passkey")
+	}
+
+	if !deleted {
+		return errs.NewNotFound(ctx, "Passkey not found")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewBaseResponse(
+			fiber.StatusOK,
+			"Passkey deleted successfully",
+		))
+}
+
+// BeginLogin returns the options for navigator.credentials.get().
+func (h *passkeyHandler) BeginLogin(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	ceremonyID, options, err := h.passkeys.BeginLogin(context)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "PasskeyHandler.BeginLogin: Failed to begin login", err)
+		return errs.NewInternalServerError(ctx, "Failed to sign in")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewPasskeyCeremonyResponse(
+			fiber.StatusOK,
+			"Passkey sign-in started",
+			ceremonyID,
+			options,
+		))
+}
+
+// FinishLogin verifies the assertion and starts a session. A passkey with user verification is
+// already two factors, so no two-factor challenge follows.
+func (h *passkeyHandler) FinishLogin(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	var request requests.PasskeyLoginRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "PasskeyHandler.FinishLogin: Failed to parse request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if err := request.Validate(); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "PasskeyHandler.FinishLogin: Invalid request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	accountID, err := h.passkeys.FinishLogin(context, uuid.MustParse(request.CeremonyID), request.Credential)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "PasskeyHandler.FinishLogin: Failed to verify passkey", err)
+		if errors.Is(err, services.ErrPasskeyCeremonyInvalid) || errors.Is(err, services.ErrPasskeyInvalid) || errors.Is(err, services.ErrPasskeyCloned) {
+			return errs.NewUnauthorized(ctx, "Passkey sign-in failed")
+		}
+		return errs.NewInternalServerError(ctx, "Failed to sign in")
+	}
+
+	account, err := h.accountRepo.FindByID(context, accountID)
+	if err != nil || account.DeletedAt.Valid {
+		logs.ErrorContext(ctx.UserContext(), "PasskeyHandler.FinishLogin: Account not found", err)
+		return errs.NewUnauthorized(ctx, "Passkey sign-in failed")
+	}
+
+	token, err := h.tokenization.GenerateToken(context, account.ID.String(), account.Roles, ctx.Get(fiber.HeaderUserAgent), ctx.IP())
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "PasskeyHandler.FinishLogin: Failed to generate token", err)
+		return errs.NewInternalServerError(ctx, "Failed to sign in")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewSignInResponse(
+			fiber.StatusOK,
+			"Sign-in successful",
+			[]*responses.TokenResponse{token},
+		))
+}
+
+// NewPasskeyHandler creates a new instance of PasskeyHandler and sets up the /api/auth/passkeys routes.
+func NewPasskeyHandler(router fiber.Router, accountRepo repositories.AccountRepository, twoFactorRepo repositories.TwoFactorRepository, passkeys services.Passkeys, tokenization services.Tokenization, cryptography services.Cryptography, totp services.TOTP, signInGuard services.SignInGuard) PasskeyHandler {
+	handler := &passkeyHandler{
+		accountRepo:   accountRepo,
+		twoFactorRepo: twoFactorRepo,
+		passkeys:      passkeys,
+		tokenization:  tokenization,
+		cryptography:  cryptography,
+		totp:          totp,
+		signInGuard:   signInGuard,
+	}
+
+	passkeyRoutes := router.Group("/api/auth/passkeys")
+	passkeyRoutes.Use(middlewares.Logger())
+
+	passkeyRoutes.Post("/login/begin", handler.BeginLogin)
+	passkeyRoutes.Post("/login/finish", handler.FinishLogin)
+
+	requireAuth := middlewares.Auth(tokenization, nil)
+
+	passkeyRoutes.Get("/", requireAuth, handler.FindAll)
+	passkeyRoutes.Post("/register/begin", requireAuth, handler.BeginRegistration)
+	passkeyRoutes.Post("/register/finish", requireAuth, handler.FinishRegistration)
+	passkeyRoutes.Delete("/:id", requireAuth, handler.Delete)
+
+	return handler
+}
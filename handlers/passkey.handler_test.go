@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"ticket-booking/entities"
+	"ticket-booking/repositories"
+	"ticket-booking/services"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// fakePasskeys counts the registration ceremonies started.
+type fakePasskeys struct {
+	services.Passkeys
+	mu    sync.Mutex
+	begun int
+}
+
+func (p *fakePasskeys) BeginRegistration(context.Context, *entities.Account) (uuid.UUID, *protocol.CredentialCreation, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.begun++
+	return uuid.New(), &protocol.CredentialCreation{}, nil
+}
+
+// fakeTOTP accepts the code "123456" at step 1.
+type fakeTOTP struct {
+	services.TOTP
+}
+
+func (t *fakeTOTP) Validate(_, code string, lastStep int64) (int64, bool) {
+	return 1, code == "123456" && lastStep < 1
+}
+
+// fakeTwoFactorRepository records the last used time step in the account.
+type fakeTwoFactorRepository struct {
+	repositories.TwoFactorRepository
+	accountRepo *fakeAccountRepository
+}
+
+func (r *fakeTwoFactorRepository) UseStep(_ context.Context, accountID uuid.UUID, step int64) (bool, error) {
+	r.accountRepo.mu.Lock()
+	defer r.accountRepo.mu.Unlock()
+
+	account := r.accountRepo.accounts[accountID]
+	if account.TOTPLastStep >= step {
+		return false, nil
+	}
+	account.TOTPLastStep = step
+	return true, nil
+}
+
+// beginPasskeyRegistration sends the body to /api/auth/passkeys/register/begin as the account.
+func beginPasskeyRegistration(t *testing.T, account *entities.Account, body string, passkeys *fakePasskeys) int {
+	t.Helper()
+
+	accountRepo := newFakeAccountRepository(account)
+	twoFactorRepo := &fakeTwoFactorRepository{accountRepo: accountRepo}
+
+	app := fiber.New()
+	NewPasskeyHandler(app, accountRepo, twoFactorRepo, passkeys, &fakeTokenization{}, &fakeCryptography{}, &fakeTOTP{}, &fakeSignInGuard{})
+
+	request := httptest.NewRequest(fiber.MethodPost, "/api/auth/passkeys/register/begin", strings.NewReader(body))
+	request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	request.Header.Set(fiber.HeaderAuthorization, "Bearer "+account.ID.String())
+
+	response, err := app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	return response.StatusCode
+}
+
+func TestPasskeyBeginRegistrationRequiresStepUp(t *testing.T) {
+	tests := []struct {
+		name        string
+		totpEnabled bool
+		lastStep    int64
+		body        string
+		status      int
+	}{
+		{name: "no password", body: `{}`, status: http.StatusBadRequest},
+		{name: "wrong password", body: `{"password":"guess"}`, status: http.StatusBadRequest},
+		{name: "password", body: `{"password":"secret-password"}`, status: http.StatusOK},
+		{name: "two-factor without code", totpEnabled: true, body: `{"password":"secret-password"}`, status: http.StatusBadRequest},
+		{name: "two-factor wrong code", totpEnabled: true, body: `{"password":"secret-password","code":"654321"}`, status: http.StatusBadRequest},
+		{name: "two-factor used code", totpEnabled: true, lastStep: 1, body: `{"password":"secret-password","code":"123456"}`, status: http.StatusBadRequest},
+		{name: "two-factor code", totpEnabled: true, body: `{"password":"secret-password","code":"123456"}`, status: http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			account := verifiedAccount("jane.doe@example.com")
+			account.Password = "hashed:secret-password"
+			account.TOTPEnabled = test.totpEnabled
+			account.TOTPSecret = sql.NullString{String: "secret", Valid: test.totpEnabled}
+			account.TOTPLastStep = test.lastStep
+			passkeys := &fakePasskeys{}
+
+			if status := beginPasskeyRegistration(t, account, test.body, passkeys); status != test.status {
+				t.Errorf("status = %d, want %d", status, test.status)
+			}
+			if started := passkeys.begun == 1; started != (test.status == http.StatusOK) {
+				t.Errorf("registration started = %v, want %v", started, test.status == http.StatusOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"ticket-booking/configs/errs"
+	"ticket-booking/configs/logs"
+	"ticket-booking/dtos/requests"
+	"ticket-booking/dtos/responses"
+	"ticket-booking/entities"
+	"ticket-booking/middlewares"
+	"ticket-booking/repositories"
+	"ticket-booking/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ProfileHandler defines methods for handling the caller's own account.
+type ProfileHandler interface {
+	FindProfile(ctx *fiber.Ctx) error
+	UpdateProfile(ctx *fiber.Ctx) error
+	ChangePassword(ctx *fiber.Ctx) error
+	DeleteAccount(ctx *fiber.Ctx) error
+}
+
+// profileHandler is an implementation of ProfileHandler for self-service account management.
+type profileHandler struct {
+	repository    repositories.AccountRepository
+	tokenization  services.Tokenization
+	cryptography  services.Cryptography
+	signInGuard   services.SignInGuard
+	accountTokens services.AccountTokens
+	accountMails  services.AccountMails
+}
+
+// newContext creates a new context with a timeout of 5 seconds for database and external calls.
+func (h *profileHandler) newContext(ctx *fiber.Ctx) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx.UserContext(), 5*time.Second)
+}
+
+// FindProfile returns the caller's account.
+func (h *profileHandler) FindProfile(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	principal := middlewares.GetPrincipal(ctx)
+
+	account, err := h.repository.FindByID(context, principal.AccountID)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "ProfileHandler.FindProfile: Account not found", err)
+		return errs.NewNotFound(ctx, "Account not found")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewProfileResponse(
+			fiber.StatusOK,
+			"Profile retrieved successfully",
+			account,
+		))
+}
+
+// UpdateProfile changes the caller's name and/or email. A new email must be confirmed with the current
+// password and takes effect once the link sent to it is followed; the current address is notified.
+func (h *profileHandler) UpdateProfile(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	principal := middlewares.GetPrincipal(ctx)
+
+	var request requests.UpdateProfileRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "ProfileHandler.UpdateProfile: Failed to parse request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if err := request.Validate(); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "ProfileHandler.UpdateProfile: Invalid request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	var account *entities.Account
+	var err error
+	if request.Email != "" {
+		account, err = verifyPassword(context, ctx, h.repository, h.cryptography, h.signInGuard, principal, request.CurrentPassword)
+		if account == nil {
+			return err
+		}
+	} else {
+		account, err = h.repository.FindByID(context, principal.AccountID)
+		if err != nil {
+			logs.ErrorContext(ctx.UserContext(), "ProfileHandler.UpdateProfile: Account not found", err)
+			return errs.NewNotFound(ctx, "Account not found")
+		}
+	}
+
+	email := entities.NormalizeEmail(request.Email)
+	emailChanged := email != "" && email != account.Email
+	if emailChanged {
+		if _, err := h.repository.FindByEmail(context, email); err == nil {
+			logs.ErrorContext(ctx.UserContext(), "ProfileHandler.UpdateProfile: Email already exists", err)
+			return errs.NewBadRequest(ctx, "Email already exists")
+		}
+	}
+
+	if request.Name != "" {
+		account.Name = request.Name
+		account.UpdatedAt = time.Now()
+
+		if err := h.repository.UpdateProfile(context, account); err != nil {
+			logs.ErrorContext(ctx.UserContext(), "ProfileHandler.UpdateProfile: Failed to update profile", err)
+			return errs.NewInternalServerError(ctx, "Failed to update profile")
+		}
+	}
+
+	if emailChanged {
+		if err := h.repository.SetPendingEmail(context, account.ID, email); err != nil {
+			logs.ErrorContext(ctx.UserContext(), "ProfileHandler.UpdateProfile: Failed to set pending email", err)
+			return errs.NewInternalServerError(ctx, "Failed to update profile")
+		}
+		account.PendingEmail = sql.NullString{String: email, Valid: true}
+
+		token, err := h.accountTokens.Issue(context, account.ID, entities.TokenPurposeEmailChange)
+		if err != nil {
+			logs.ErrorContext(ctx.UserContext(), "ProfileHandler.UpdateProfile: Failed to issue email change token", err)
+			if errors.Is(err, services.ErrAccountTokenRateLimited) {
+				return errs.NewTooManyRequests(ctx, "Too many email changes requested, try again later")
+			}
+			return errs.NewInternalServerError(ctx, "Failed to update profile")
+		}
+
+		if err := h.accountMails.SendEmailChange(context, account, email, token); err != nil {
+			logs.ErrorContext(ctx.UserContext(), "ProfileHandler.UpdateProfile: Failed to send email change confirmation", err)
+			return errs.NewInternalServerError(ctx, "Failed to send the confirmation email")
+		}
+
+		// The change is pending either way, the notice only warns the owner of the current address
+		if err := h.accountMails.SendEmailChangeNotice(context, account, email); err != nil {
+			logs.ErrorContext(ctx.UserContext(), "ProfileHandler.UpdateProfile: Failed to send email change notice", err)
+		}
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewProfileResponse(
+			fiber.StatusOK,
+			"Profile updated successfully",
+			account,
+		))
+}
+
+// ChangePassword replaces the caller's password and logs out every other session.
+func (h *profileHandler) ChangePassword(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	principal := middlewares.GetPrincipal(ctx)
+
+	var request requests.ChangePasswordRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "ProfileHandler.ChangePassword: Failed to parse request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if err := request.Validate(); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "ProfileHandler.ChangePassword: Invalid request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	account, err := verifyPassword(context, ctx, h.repository, h.cryptography, h.signInGuard, principal, request.CurrentPassword)
+	if account == nil {
+		return err
+	}
+
+	hashedPassword, err := h.cryptography.EncryptPassword(request.NewPassword)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "ProfileHandler.ChangePassword: Failed to encrypt password", err)
+		return errs.NewInternalServerError(ctx, "Failed to change password")
+	}
+
+	if err := h.repository.UpdatePassword(context, account.ID, hashedPassword); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "ProfileHandler.ChangePassword: Failed to update password", err)
+		return errs.NewInternalServerError(ctx, "Failed to change password")
+	}
+
+	if err := h.tokenization.RevokeOtherSessions(context, principal); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "ProfileHandler.ChangePassword: Failed to revoke other sessions", err)
+		return errs.NewInternalServerError(ctx, "Failed to change password")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewBaseResponse(
+			fiber.StatusOK,
+			"Password changed successfully",
+		))
+}
+
+// DeleteAccount anonymises the caller's account and logs out every session.
+// Tickets are kept, still pointing at the anonymised account.
+func (h *profileHandler) DeleteAccount(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	principal := middlewares.GetPrincipal(ctx)
+
+	var request requests.DeleteAccountRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "ProfileHandler.DeleteAccount: Failed to parse request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if err := request.Validate(); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "ProfileHandler.DeleteAccount: Invalid request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	account, err := verifyPassword(context, ctx, h.repository, h.cryptography, h.signInGuard, principal, request.Password)
+	if account == nil {
+		return err
+	}
+
+	if err := h.repository.Anonymize(context, account.ID); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "ProfileHandler.DeleteAccount: Failed to anonymize account", err)
+		return errs.NewInternalServerError(ctx, "Failed to delete account")
+	}
+
+	if err := h.tokenization.RevokeAllSessions(context, account.ID); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "ProfileHandler.DeleteAccount: Failed to revoke sessions", err)
+		return errs.NewInternalServerError(ctx, "Failed to delete account")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewBaseResponse(
+			fiber.StatusOK,
+			"Account deleted successfully",
+		))
+}
+
+// verifyPassword loads the caller's account and checks the password they confirmed with. Wrong
+// passwords count as failed sign-ins, so a stolen access token cannot be used to guess the password.
+// On failure it returns a nil account and the error response already written to ctx.
+func verifyPassword(context context.Context, ctx *fiber.Ctx, repository repositories.AccountRepository, cryptography services.Cryptography, signInGuard services.SignInGuard, principal *entities.Principal, password string) (*entities.Account, error) {
+	if wait := signInGuard.Throttled(ctx.IP()); wait > 0 {
+		logs.WarnContext(ctx.UserContext(), "Handler.verifyPassword: Too many failed attempts from client")
+		ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(wait.Seconds())+1))
+		return nil, errs.NewTooManyRequests(ctx, "Too many attempts, try again later")
+	}
+
+	account, err := repository.FindByID(context, principal.AccountID)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "Handler.verifyPassword: Account not found", err)
+		return nil, errs.NewNotFound(ctx, "Account not found")
+	}
+
+	if now := time.Now(); account.IsLocked(now) {
+		logs.WarnContext(ctx.UserContext(), "Handler.verifyPassword: Account locked")
+		ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(account.LockedUntil.Sub(now).Seconds())+1))
+		return nil, errs.NewTooManyRequests(ctx, "Too many attempts, try again later")
+	}
+
+	match, _, err := cryptography.VerifyPassword(password, account.Password)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "Handler.verifyPassword: Failed to verify password", err)
+		return nil, errs.NewInternalServerError(ctx, "Failed to verify password")
+	}
+
+	if !match {
+		logs.ErrorContext(ctx.UserContext(), "Handler.verifyPassword: Incorrect password", nil)
+		signInGuard.Failed(context, ctx.IP(), account)
+		return nil, errs.NewBadRequest(ctx, "Incorrect password")
+	}
+
+	signInGuard.Succeeded(context, ctx.IP(), account)
+
+	return account, nil
+}
+
+// NewProfileHandler initializes a new instance of profileHandler and sets up the /api/accounts/me routes.
+func NewProfileHandler(router fiber.Router, repository repositories.AccountRepository, tokenization services.Tokenization, cryptography services.Cryptography, accountTokens services.AccountTokens, accountMails services.AccountMails, signInGuard services.SignInGuard) ProfileHandler {
+	handler := &profileHandler{
+		repository:    repository,
+		tokenization:  tokenization,
+		cryptography:  cryptography,
+		signInGuard:   signInGuard,
+		accountTokens: accountTokens,
+		accountMails:  accountMails,
+	}
+
+	profileRoutes := router.Group("/api/accounts/me")
+	profileRoutes.Use(middlewares.Logger())
+	profileRoutes.Use(middlewares.Auth(tokenization, nil))
+
+	profileRoutes.Get("/", handler.FindProfile)
+	profileRoutes.Patch("/", handler.UpdateProfile)
+	profileRoutes.Delete("/", handler.DeleteAccount)
+	profileRoutes.Post("/password", handler.ChangePassword)
+
+	return handler
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ticket-booking/entities"
+	"ticket-booking/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// fakeSignInGuard never throttles and counts the failed attempts.
+type fakeSignInGuard struct {
+	mu       sync.Mutex
+	failures int
+}
+
+func (g *fakeSignInGuard) Throttled(string) time.Duration {
+	return 0
+}
+
+func (g *fakeSignInGuard) Failed(context.Context, string, *entities.Account) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.failures++
+}
+
+func (g *fakeSignInGuard) Succeeded(context.Context, string, *entities.Account) {}
+
+// fakeAccountTokens issues the purpose as token.
+type fakeAccountTokens struct {
+	services.AccountTokens
+}
+
+func (a *fakeAccountTokens) Issue(_ context.Context, _ uuid.UUID, purpose entities.TokenPurpose) (string, error) {
+	return string(purpose), nil
+}
+
+// fakeAccountMails records the recipient of each email.
+type fakeAccountMails struct {
+	services.AccountMails
+	mu   sync.Mutex
+	sent map[string]string
+}
+
+func (m *fakeAccountMails) record(kind, to string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.sent == nil {
+		m.sent = make(map[string]string)
+	}
+	m.sent[kind] = to
+	return nil
+}
+
+func (m *fakeAccountMails) SendEmailChange(_ context.Context, _ *entities.Account, email, _ string) error {
+	return m.record("email change", email)
+}
+
+func (m *fakeAccountMails) SendEmailChangeNotice(_ context.Context, account *entities.Account, _ string) error {
+	return m.record("email change notice", account.Email)
+}
+
+// updateProfile sends the body to PATCH /api/accounts/me as the account.
+func updateProfile(t *testing.T, account *entities.Account, body string, accountRepo *fakeAccountRepository, guard *fakeSignInGuard, mails *fakeAccountMails) int {
+	t.Helper()
+
+	app := fiber.New()
+	NewProfileHandler(app, accountRepo, &fakeTokenization{}, &fakeCryptography{}, &fakeAccountTokens{}, mails, guard)
+
+	request := httptest.NewRequest(fiber.MethodPatch, "/api/accounts/me/", strings.NewReader(body))
+	request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	request.Header.Set(fiber.HeaderAuthorization, "Bearer "+account.ID.String())
+
+	response, err := app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	return response.StatusCode
+}
+
+func TestUpdateProfileEmailRequiresPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		failures int
+	}{
+		{name: "no password", body: `{"email":"new@example.com"}`},
+		{name: "wrong password", body: `{"email":"new@example.com","current_password":"guess"}`, failures: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			account := verifiedAccount("jane.doe@example.com")
+			account.Password = "hashed:secret-password"
+			accountRepo := newFakeAccountRepository(account)
+			guard := &fakeSignInGuard{}
+			mails := &fakeAccountMails{}
+
+			if status := updateProfile(t, account, test.body, accountRepo, guard, mails); status != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", status, http.StatusBadRequest)
+			}
+			if account.PendingEmail.Valid {
+				t.Errorf("pending email set to %q", account.PendingEmail.String)
+			}
+			if len(mails.sent) != 0 {
+				t.Errorf("emails sent: %v", mails.sent)
+			}
+			if guard.failures != test.failures {
+				t.Errorf("failed attempts = %d, want %d", guard.failures, test.failures)
+			}
+		})
+	}
+}
+
+func TestUpdateProfileEmailWaitsForConfirmation(t *testing.T) {
+	account := verifiedAccount("jane.doe@example.com")
+	account.Password = "hashed:secret-password"
+	accountRepo := newFakeAccountRepository(account)
+	mails := &fakeAccountMails{}
+
+	body := `{"email":"New@Example.com","current_password":"secret-password"}`
+	if status := updateProfile(t, account, body, accountRepo, &fakeSignInGuard{}, mails); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+
+	if account.Email != "jane.doe@example.com" || !account.IsEmailVerified() {
+		t.Errorf("email = %q (verified %v), want the current address until confirmed", account.Email, account.IsEmailVerified())
+	}
+	if account.PendingEmail.String != "new@example.com" {
+		t.Errorf("pending email = %q, want new@example.com", account.PendingEmail.String)
+	}
+	if to := mails.sent["email change"]; to != "new@example.com" {
+		t.Errorf("confirmation sent to %q, want the new address", to)
+	}
+	if to := mails.sent["email change notice"]; to != "jane.doe@example.com" {
+		t.Errorf("notice sent to %q, want the current address", to)
+	}
+}
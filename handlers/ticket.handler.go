@@ -4,11 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"strconv"
-	"strings"
 	"ticket-booking/configs/errs"
 	"ticket-booking/configs/logs"
-	"ticket-booking/dtos/requests"
+	"ticket-booking/configs/metrics"
 	"ticket-booking/dtos/responses"
 	"ticket-booking/entities"
 	"ticket-booking/middlewares"
@@ -35,39 +33,27 @@ type ticketHandler struct {
 	cryptography services.Cryptography
 }
 
-func (t *ticketHandler) newContext() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), 5*time.Second)
+func (t *ticketHandler) newContext(ctx *fiber.Ctx) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx.UserContext(), 5*time.Second)
 }
 
 func (t *ticketHandler) Validate(ctx *fiber.Ctx) error {
-	context, cancel := t.newContext()
+	context, cancel := t.newContext(ctx)
 	defer cancel()
 
-	token := strings.TrimPrefix(ctx.Get("Authorization"), "Bearer ")
-	accountID, err := t.tokenization.GetAccountID(token)
+	id, err := entities.ParsePublicID(ctx.Params("id"))
 	if err != nil {
-		logs.Error("TicketHandler.Validate: Invalid ID parameter", err)
+		logs.ErrorContext(ctx.UserContext(), "TicketHandler.Validate: Invalid ID parameter", err)
 		return errs.NewBadRequest(ctx, "Invalid parameter")
 	}
 
-	id, err := strconv.ParseUint(ctx.Params("id"), 10, 64) // or 32 if it's a smaller range
-	if err != nil {
-		logs.Error("EventHandler.FindByID: Invalid ID parameter", err)
-		return errs.NewBadRequest(ctx, "Invalid parameter")
-	}
-
-	var request requests.EventRequest
-	if err := ctx.BodyParser(&request); err != nil {
-		logs.Error("TicketHandler.Update: Failed to parse request body", err)
-		return errs.NewBadRequest(ctx, "Invalid parameter")
-	}
-
-	ticket, err := t.ticketRepo.FindByID(context, accountID, id)
+	// Staff validate tickets owned by other accounts, so the lookup is not scoped to the caller
+	ticket, err := t.ticketRepo.FindByPublicIDUnscoped(context, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return errs.NewNotFound(ctx, "Ticket not found")
 		}
-		logs.Error("TicketHandler.Validate: Failed to retrieve ticket by ID", err)
+		logs.ErrorContext(ctx.UserContext(), "TicketHandler.Validate: Failed to retrieve ticket by ID", err)
 		return errs.NewInternalServerError(ctx, "Failed to retrieve tickets")
 	}
 
@@ -78,7 +64,11 @@ func (t *ticketHandler) Validate(ctx *fiber.Ctx) error {
 	ticket.Entered = true
 	ticket.UpdatedAt = time.Now()
 
-	t.ticketRepo.Validate(context, ticket)
+	if err := t.ticketRepo.Validate(context, ticket); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TicketHandler.Validate: Failed to validate ticket", err)
+		return errs.NewInternalServerError(ctx, "Failed to validate ticket")
+	}
+	metrics.CheckIns.Inc()
 
 	return ctx.Status(fiber.StatusNoContent).JSON(
 		responses.NewBaseResponse(
@@ -88,36 +78,32 @@ func (t *ticketHandler) Validate(ctx *fiber.Ctx) error {
 }
 
 func (t *ticketHandler) Create(ctx *fiber.Ctx) error {
-	context, cancel := t.newContext()
+	context, cancel := t.newContext(ctx)
 	defer cancel()
 
-	token := strings.TrimPrefix(ctx.Get("Authorization"), "Bearer ")
-	accountID, err := t.tokenization.GetAccountID(token)
-	if err != nil {
-		logs.Error("TicketHandler.Create: Invalid ID parameter", err)
-		return errs.NewBadRequest(ctx, "Invalid parameter")
-	}
+	accountID := middlewares.GetPrincipal(ctx).AccountID
 
-	id, err := strconv.ParseUint(ctx.Params("id"), 10, 64) // or 32 if it's a smaller range
+	id, err := entities.ParsePublicID(ctx.Params("id"))
 	if err != nil {
-		logs.Error("EventHandler.FindByID: Invalid ID parameter", err)
+		logs.ErrorContext(ctx.UserContext(), "TicketHandler.Create: Invalid ID parameter", err)
 		return errs.NewBadRequest(ctx, "Invalid parameter")
 	}
 
-	event, err := t.eventRepo.FindByID(context, id)
+	event, err := t.eventRepo.FindByPublicID(context, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return errs.NewNotFound(ctx, "Event not found")
 		}
-		logs.Error("TicketHandler.Create: Failed to retrieve event by ID", err)
+		logs.ErrorContext(ctx.UserContext(), "TicketHandler.Create: Failed to retrieve event by ID", err)
 		return errs.NewInternalServerError(ctx, "Failed to retrieve events")
 	}
 
 	err = t.ticketRepo.Create(context, entities.NewTicket(event.ID, accountID))
 	if err != nil {
-		logs.Error("TicketHandler.Create: Failed to create Ticket", err)
+		logs.ErrorContext(ctx.UserContext(), "TicketHandler.Create: Failed to create Ticket", err)
 		return errs.NewInternalServerError(ctx, "Failed to create Ticket")
 	}
+	metrics.TicketsIssued.Inc()
 
 	return ctx.Status(fiber.StatusCreated).JSON(
 		responses.NewBaseResponse(
@@ -127,36 +113,32 @@ func (t *ticketHandler) Create(ctx *fiber.Ctx) error {
 }
 
 func (t *ticketHandler) Delete(ctx *fiber.Ctx) error {
-	context, cancel := t.newContext()
+	context, cancel := t.newContext(ctx)
 	defer cancel()
 
-	token := strings.TrimPrefix(ctx.Get("Authorization"), "Bearer ")
-	accountID, err := t.tokenization.GetAccountID(token)
-	if err != nil {
-		logs.Error("TicketHandler.Create: Invalid ID parameter", err)
-		return errs.NewBadRequest(ctx, "Invalid parameter")
-	}
+	accountID := middlewares.GetPrincipal(ctx).AccountID
 
-	id, err := strconv.ParseUint(ctx.Params("id"), 10, 64) // or 32 if it's a smaller range
+	id, err := entities.ParsePublicID(ctx.Params("id"))
 	if err != nil {
-		logs.Error("EventHandler.FindByID: Invalid ID parameter", err)
+		logs.ErrorContext(ctx.UserContext(), "TicketHandler.Delete: Invalid ID parameter", err)
 		return errs.NewBadRequest(ctx, "Invalid parameter")
 	}
 
-	ticket, err := t.ticketRepo.FindByID(context, accountID, id)
+	ticket, err := t.ticketRepo.FindByPublicID(context, accountID, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return errs.NewNotFound(ctx, "Ticket not found")
 		}
-		logs.Error("TicketHandler.Delete: Failed to retrieve event by ID", err)
+		logs.ErrorContext(ctx.UserContext(), "TicketHandler.Delete: Failed to retrieve event by ID", err)
 		return errs.NewInternalServerError(ctx, "Failed to retrieve tickets")
 	}
 
 	err = t.ticketRepo.Delete(context, ticket.AccountID, ticket.ID)
 	if err != nil {
-		logs.Error("TicketHandler.Delete: Failed to delete ticket", err)
+		logs.ErrorContext(ctx.UserContext(), "TicketHandler.Delete: Failed to delete ticket", err)
 		return errs.NewInternalServerError(ctx, "Failed to delete ticket")
 	}
+	metrics.Refunds.Inc()
 
 	return ctx.Status(fiber.StatusNoContent).JSON(
 		responses.NewBaseResponse(
@@ -166,15 +148,10 @@ func (t *ticketHandler) Delete(ctx *fiber.Ctx) error {
 }
 
 func (t *ticketHandler) FindAll(ctx *fiber.Ctx) error {
-	context, cancel := t.newContext()
+	context, cancel := t.newContext(ctx)
 	defer cancel()
 
-	token := strings.TrimPrefix(ctx.Get("Authorization"), "Bearer ")
-	accountID, err := t.tokenization.GetAccountID(token)
-	if err != nil {
-		logs.Error("TicketHandler.Create: Invalid ID parameter", err)
-		return errs.NewBadRequest(ctx, "Invalid parameter")
-	}
+	accountID := middlewares.GetPrincipal(ctx).AccountID
 
 	tickets, err := t.ticketRepo.FindAll(context, accountID)
 	if err != nil {
@@ -182,7 +159,7 @@ func (t *ticketHandler) FindAll(ctx *fiber.Ctx) error {
 			return errs.NewNotFound(ctx, "Ticket not found")
 		}
 
-		logs.Error("TicketHandler.FindAll: Failed to retrieve tickets", err)
+		logs.ErrorContext(ctx.UserContext(), "TicketHandler.FindAll: Failed to retrieve tickets", err)
 		return errs.NewInternalServerError(ctx, "Failed to retrieve tickets")
 	}
 
@@ -196,7 +173,7 @@ func (t *ticketHandler) FindAll(ctx *fiber.Ctx) error {
 			if err == sql.ErrNoRows {
 				return errs.NewNotFound(ctx, "Event not found")
 			}
-			logs.Error("TicketHandler.FindAll: Failed to retrieve event for ticket", err)
+			logs.ErrorContext(ctx.UserContext(), "TicketHandler.FindAll: Failed to retrieve event for ticket", err)
 			return errs.NewInternalServerError(ctx, "Failed to retrieve events for tickets")
 		}
 
@@ -212,28 +189,23 @@ func (t *ticketHandler) FindAll(ctx *fiber.Ctx) error {
 }
 
 func (t *ticketHandler) FindByID(ctx *fiber.Ctx) error {
-	context, cancel := t.newContext()
+	context, cancel := t.newContext(ctx)
 	defer cancel()
 
-	token := strings.TrimPrefix(ctx.Get("Authorization"), "Bearer ")
-	accountID, err := t.tokenization.GetAccountID(token)
-	if err != nil {
-		logs.Error("TicketHandler.Create: Invalid ID parameter", err)
-		return errs.NewBadRequest(ctx, "Invalid parameter")
-	}
+	accountID := middlewares.GetPrincipal(ctx).AccountID
 
-	id, err := strconv.ParseUint(ctx.Params("id"), 10, 64) // or 32 if it's a smaller range
+	id, err := entities.ParsePublicID(ctx.Params("id"))
 	if err != nil {
-		logs.Error("EventHandler.FindByID: Invalid ID parameter", err)
+		logs.ErrorContext(ctx.UserContext(), "TicketHandler.FindByID: Invalid ID parameter", err)
 		return errs.NewBadRequest(ctx, "Invalid parameter")
 	}
 
-	ticket, err := t.ticketRepo.FindByID(context, accountID, id)
+	ticket, err := t.ticketRepo.FindByPublicID(context, accountID, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return errs.NewNotFound(ctx, "Ticket not found")
 		}
-		logs.Error("TicketHandler.FindByID: Failed to retrieve ticket by ID", err)
+		logs.ErrorContext(ctx.UserContext(), "TicketHandler.FindByID: Failed to retrieve ticket by ID", err)
 		return errs.NewInternalServerError(ctx, "Failed to retrieve tickets")
 	}
 
@@ -243,7 +215,7 @@ func (t *ticketHandler) FindByID(ctx *fiber.Ctx) error {
 		256,
 	)
 	if err != nil {
-		logs.Error("TicketHandler.FindByID: Failed to generate QR code", err)
+		logs.ErrorContext(ctx.UserContext(), "TicketHandler.FindByID: Failed to generate QR code", err)
 		return errs.NewInternalServerError(ctx, "Failed to generate QR code")
 	}
 
@@ -252,7 +224,7 @@ func (t *ticketHandler) FindByID(ctx *fiber.Ctx) error {
 		if err == sql.ErrNoRows {
 			return errs.NewNotFound(ctx, "Event not found")
 		}
-		logs.Error("TicketHandler.FindByID: Failed to retrieve event by ID", err)
+		logs.ErrorContext(ctx.UserContext(), "TicketHandler.FindByID: Failed to retrieve event by ID", err)
 		return errs.NewInternalServerError(ctx, "Failed to retrieve events")
 	}
 
@@ -266,7 +238,7 @@ func (t *ticketHandler) FindByID(ctx *fiber.Ctx) error {
 	))
 }
 
-func NewTicketHandler(router fiber.Router, ticketRepo repositories.TicketRepository, eventRepo repositories.EventRepository, tokenization services.Tokenization) TicketHandler {
+func NewTicketHandler(router fiber.Router, ticketRepo repositories.TicketRepository, eventRepo repositories.EventRepository, accountRepo repositories.AccountRepository, tokenization services.Tokenization, apiKeys services.APIKeys) TicketHandler {
 	handler := &ticketHandler{
 		ticketRepo:   ticketRepo,
 		eventRepo:    eventRepo,
@@ -276,13 +248,18 @@ func NewTicketHandler(router fiber.Router, ticketRepo repositories.TicketReposit
 	ticketRoutes := router.Group("/api/tickets")
 
 	ticketRoutes.Use(middlewares.Logger())
-	ticketRoutes.Use(middlewares.Auth(tokenization))
-
-	ticketRoutes.Get("/", handler.FindAll)
-	ticketRoutes.Post("/:id", handler.Create)   // Create a new Ticket
-	ticketRoutes.Get("/:id", handler.FindByID)  // Retrieve an Ticket by ID
-	ticketRoutes.Delete("/:id", handler.Delete) // Delete an Ticket by ID
-	ticketRoutes.Put("/:id", handler.Validate)  // Validate a ticket
+	ticketRoutes.Use(middlewares.Auth(tokenization, apiKeys))
+
+	canRead := middlewares.RequirePermission(entities.PermissionTicketsRead)
+	canWrite := middlewares.RequirePermission(entities.PermissionTicketsWrite)
+	canScan := middlewares.RequirePermission(entities.PermissionTicketsScan)
+	isVerified := middlewares.RequireVerifiedEmail(accountRepo)
+
+	ticketRoutes.Get("/", canRead, handler.FindAll)
+	ticketRoutes.Post("/:id", canWrite, isVerified, handler.Create) // Create a new Ticket
+	ticketRoutes.Get("/:id", canRead, handler.FindByID)             // Retrieve an Ticket by ID
+	ticketRoutes.Delete("/:id", canWrite, handler.Delete)           // Delete an Ticket by ID
+	ticketRoutes.Put("/:id", canScan, handler.Validate)             // Validate a ticket
 
 	return handler
 }
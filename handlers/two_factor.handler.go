@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"ticket-booking/configs/errs"
+	"ticket-booking/configs/logs"
+	"ticket-booking/dtos/requests"
+	"ticket-booking/dtos/responses"
+	"ticket-booking/entities"
+	"ticket-booking/middlewares"
+	"ticket-booking/repositories"
+	"ticket-booking/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TwoFactorHandler defines methods for handling two-factor authentication routes.
+type TwoFactorHandler interface {
+	Enroll(ctx *fiber.Ctx) error
+	Confirm(ctx *fiber.Ctx) error
+	Disable(ctx *fiber.Ctx) error
+	Verify(ctx *fiber.Ctx) error
+}
+
+// twoFactorHandler is an implementation of TwoFactorHandler based on TOTP.
+type twoFactorHandler struct {
+	accountRepo   repositories.AccountRepository
+	twoFactorRepo repositories.TwoFactorRepository
+	tokenization  services.Tokenization
+	totp          services.TOTP
+	signInGuard   services.SignInGuard
+}
+
+// Enroll generates a new TOTP secret for the caller. It is not enforced until confirmed.
+func (h *twoFactorHandler) Enroll(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	account, err := h.accountRepo.FindByID(context, middlewares.GetPrincipal(ctx).AccountID)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TwoFactorHandler.Enroll: Failed to retrieve account", err)
+		return errs.NewInternalServerError(ctx, "Failed to enroll two-factor authentication")
+	}
+
+	if account.TOTPEnabled {
+		return errs.NewBadRequest(ctx, "Two-factor authentication already enabled")
+	}
+
+	secret, err := h.totp.GenerateSecret()
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TwoFactorHandler.Enroll: Failed to generate secret", err)
+		return errs.NewInternalServerError(ctx, "Failed to enroll two-factor authentication")
+	}
+
+	uri := h.totp.URI(secret, account.Email)
+	qr, err := h.totp.QRCode(uri)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TwoFactorHandler.Enroll: Failed to generate QR code", err)
+		return errs.NewInternalServerError(ctx, "Failed to generate QR code")
+	}
+
+	if err := h.twoFactorRepo.SetSecret(context, account.ID, secret); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TwoFactorHandler.Enroll: Failed to store secret", err)
+		return errs.NewInternalServerError(ctx, "Failed to enroll two-factor authentication")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewTwoFactorEnrollmentResponse(
+			fiber.StatusOK,
+			"Scan the QR code and confirm with a code from your authenticator app",
+			secret,
+			uri,
+			qr,
+		))
+}
+
+// Confirm enables two-factor authentication once the caller proves the secret was enrolled,
+// and returns the recovery codes.
+func (h *twoFactorHandler) Confirm(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	var request requests.TwoFactorCodeRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TwoFactorHandler.Confirm: Failed to parse request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if err := request.Validate(); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TwoFactorHandler.Confirm: Invalid request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	account, err := h.accountRepo.FindByID(context, middlewares.GetPrincipal(ctx).AccountID)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TwoFactorHandler.Confirm: Failed to retrieve account", err)
+		return errs.NewInternalServerError(ctx, "Failed to confirm two-factor authentication")
+	}
+
+	if account.TOTPEnabled {
+		return errs.NewBadRequest(ctx, "Two-factor authentication already enabled")
+	}
+
+	if !account.TOTPSecret.Valid {
+		return errs.NewBadRequest(ctx, "Two-factor authentication not enrolled")
+	}
+
+	step, ok := h.totp.Validate(account.TOTPSecret.String, request.Code, account.TOTPLastStep)
+	if !ok {
+		logs.WarnContext(ctx.UserContext(), "TwoFactorHandler.Confirm: Invalid code")
+		return errs.NewBadRequest(ctx, "Invalid code")
+	}
+
+	codes, err := h.totp.GenerateRecoveryCodes()
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TwoFactorHandler.Confirm: Failed to generate recovery codes", err)
+		return errs.NewInternalServerError(ctx, "Failed to confirm two-factor authentication")
+	}
+
+	recoveryCodes := make([]*entities.RecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		recoveryCodes = append(recoveryCodes, entities.NewRecoveryCode(account.ID, h.totp.HashRecoveryCode(code)))
+	}
+
+	if err := h.twoFactorRepo.Enable(context, account.ID, step, recoveryCodes); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TwoFactorHandler.Confirm: Failed to enable two-factor authentication", err)
+		return errs.NewInternalServerError(ctx, "Failed to confirm two-factor authentication")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewRecoveryCodesResponse(
+			fiber.StatusOK,
+			"Two-factor authentication enabled, store these recovery codes safely",
+			codes,
+		))
+}
+
+// Disable turns two-factor authentication off, given a current code.
+func (h *twoFactorHandler) Disable(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	var request requests.TwoFactorCodeRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TwoFactorHandler.Disable: Failed to parse request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if err := request.Validate(); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TwoFactorHandler.Disable: Invalid request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	account, err := h.accountRepo.FindByID(context, middlewares.GetPrincipal(ctx).AccountID)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TwoFactorHandler.Disable: Failed to retrieve account", err)
+		return errs.NewInternalServerError(ctx, "Failed to disable two-factor authentication")
+	}
+
+	if !account.TOTPEnabled {
+		return errs.NewBadRequest(ctx, "Two-factor authentication not enabled")
+	}
+
+	verified, err := useTOTPCode(context, h.totp, h.twoFactorRepo, account, request.Code)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TwoFactorHandler.Disable: Failed to verify code", err)
+		return errs.NewInternalServerError(ctx, "Failed to disable two-factor authentication")
+	}
+
+	if !verified {
+		logs.WarnContext(ctx.UserContext(), "TwoFactorHandler.Disable: Invalid code")
+		return errs.NewBadRequest(ctx, "Invalid code")
+	}
+
+	if err := h.twoFactorRepo.Disable(context, account.ID); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TwoFactorHandler.Disable: Failed to disable two-factor authentication", err)
+		return errs.NewInternalServerError(ctx, "Failed to disable two-factor authentication")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewBaseResponse(
+			fiber.StatusOK,
+			"Two-factor authentication disabled",
+		))
+}
+
+// Verify completes a two-factor sign-in, exchanging the challenge and a code for a session. Wrong codes
+// count as failed sign-ins of the account, so the challenge cannot be replayed to guess the code.
+func (h *twoFactorHandler) Verify(ctx *fiber.Ctx) error {
+	context, cancel := h.newContext(ctx)
+	defer cancel()
+
+	var request requests.TwoFactorVerifyRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TwoFactorHandler.Verify: Failed to parse request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if err := request.Validate(); err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TwoFactorHandler.Verify: Invalid request body", err)
+		return errs.NewBadRequest(ctx, "Invalid parameter")
+	}
+
+	if wait := h.signInGuard.Throttled(ctx.IP()); wait > 0 {
+		logs.WarnContext(ctx.UserContext(), "TwoFactorHandler.Verify: Too many failed attempts from client")
+		ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(wait.Seconds())+1))
+		return errs.NewTooManyRequests(ctx, "Too many sign-in attempts, try again later")
+	}
+
+	accountID, err := h.tokenization.ParseChallengeToken(request.Challenge)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TwoFactorHandler.Verify: Invalid challenge", err)
+		return errs.NewUnauthorized(ctx, "Invalid or expired challenge")
+	}
+
+	account, err := h.accountRepo.FindByID(context, accountID)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TwoFactorHandler.Verify: Failed to retrieve account", err)
+		return errs.NewUnauthorized(ctx, "Invalid or expired challenge")
+	}
+
+	if !account.TOTPEnabled {
+		return errs.NewUnauthorized(ctx, "Invalid or expired challenge")
+	}
+
+	// A lockout earned here or at the password step also stops the challenges already issued
+	if account.IsLocked(time.Now()) {
+		logs.WarnContext(ctx.UserContext(), "TwoFactorHandler.Verify: Account locked")
+		h.signInGuard.Failed(context, ctx.IP(), account)
+		return errs.NewUnauthorized(ctx, "Invalid code")
+	}
+
+	var verified bool
+	if request.Code != "" {
+		verified, err = useTOTPCode(context, h.totp, h.twoFactorRepo, account, request.Code)
+	} else {
+		verified, err = h.twoFactorRepo.UseRecoveryCode(context, account.ID, h.totp.HashRecoveryCode(request.RecoveryCode))
+	}
+
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TwoFactorHandler.Verify: Failed to verify code", err)
+		return errs.NewInternalServerError(ctx, "Failed to sign in")
+	}
+
+	if !verified {
+		logs.WarnContext(ctx.UserContext(), "TwoFactorHandler.Verify: Invalid code")
+		h.signInGuard.Failed(context, ctx.IP(), account)
+		return errs.NewUnauthorized(ctx, "Invalid code")
+	}
+
+	h.signInGuard.Succeeded(context, ctx.IP(), account)
+
+	token, err := h.tokenization.GenerateToken(context, account.ID.String(), account.Roles, ctx.Get(fiber.HeaderUserAgent), ctx.IP())
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "TwoFactorHandler.Verify: Failed to generate token", err)
+		return errs.NewInternalServerError(ctx, "Failed to sign in")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		responses.NewSignInResponse(
+			fiber.StatusOK,
+			"Sign-in successful",
+			[]*responses.TokenResponse{token},
+		))
+}
+
+// useTOTPCode checks a code of the account and records its time step, so the code cannot be used
+// again. It returns false for a wrong code and for a step that was already used.
+func useTOTPCode(context context.Context, totp services.TOTP, twoFactorRepo repositories.TwoFactorRepository, account *entities.Account, code string) (bool, error) {
+	step, ok := totp.Validate(account.TOTPSecret.String, code, account.TOTPLastStep)
+	if !ok {
+		return false, nil
+	}
+
+	return twoFactorRepo.UseStep(context, account.ID, step)
+}
+
+// newContext creates a new context with a timeout of 5 seconds for database calls.
+func (h *twoFactorHandler) newContext(ctx *fiber.Ctx) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx.UserContext(), 5*time.Second)
+}
+
+// NewTwoFactorHandler creates a new instance of TwoFactorHandler and sets up the two-factor routes.
+func NewTwoFactorHandler(router fiber.Router, accountRepo repositories.AccountRepository, twoFactorRepo repositories.TwoFactorRepository, tokenization services.Tokenization, totp services.TOTP, signInGuard services.SignInGuard) TwoFactorHandler {
+	handler := &twoFactorHandler{
+		accountRepo:   accountRepo,
+		twoFactorRepo: twoFactorRepo,
+		tokenization:  tokenization,
+		totp:          totp,
+		signInGuard:   signInGuard,
+	}
+
+	twoFactorRoutes := router.Group("/api/auth/2fa")
+	twoFactorRoutes.Use(middlewares.Logger())
+
+	requireAuth := middlewares.Auth(tokenization, nil)
+
+	twoFactorRoutes.Post("/enroll", requireAuth, handler.Enroll)
+	twoFactorRoutes.Post("/confirm", requireAuth, handler.Confirm)
+	twoFactorRoutes.Post("/disable", requireAuth, handler.Disable)
+	twoFactorRoutes.Post("/verify", handler.Verify)
+
+	return handler
+}
@@ -1,9 +1,22 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"ticket-booking/configs"
 	"ticket-booking/configs/logs"
+	"ticket-booking/configs/metrics"
 	"ticket-booking/handlers"
+	"ticket-booking/middlewares"
+	"ticket-booking/migrations"
 	"ticket-booking/repositories"
 	"ticket-booking/services"
 
@@ -13,50 +26,182 @@ import (
 )
 
 func main() {
-	// Load environment variables from .env file
-	err := godotenv.Load()
-	if err != nil {
+	// Load environment variables from an optional .env file; containers usually set them directly
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
 		logs.Fatal("Error loading .env file", err)
 	}
 
-	// Create database connections using the configured functions
-	reader := configs.GetReaderSqlx()
-	writer := configs.GetWriterSqlx()
+	config, err := configs.Load(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid configuration:", err)
+		logs.Fatal("Invalid configuration", err)
+	}
 
-	// Ensure connections are closed when the application exits
-	defer func() {
-		if err := reader.Close(); err != nil {
-			logs.Error("Error closing reader", err)
+	if config.PrintConfig {
+		if err := config.Print(os.Stdout); err != nil {
+			logs.Fatal("Error printing configuration", err)
 		}
-	}()
-	defer func() {
-		if err := writer.Close(); err != nil {
-			logs.Error("Error closing writer", err)
+		return
+	}
+
+	if err := logs.Configure(config.Log.Options()); err != nil {
+		logs.Fatal("Error configuring the logs", err)
+	}
+	if err := logs.SetLogLevel(config.Log.Level); err != nil {
+		logs.Fatal("Error setting the log level", err)
+	}
+
+	if len(config.Args) > 0 && config.Args[0] != "migrate" {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n%s\n", config.Args[0], migrateUsage)
+		os.Exit(2)
+	}
+
+	// Create database connections: writes go to the primary, reads to the replicas when there are any
+	writer, err := configs.GetWriterSqlx(config.Database)
+	if err != nil {
+		logs.Fatal("Error connecting to the database", err)
+	}
+
+	if len(config.Args) > 0 {
+		err := runMigrate(writer.DB, config.Args[1:])
+		_ = writer.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
-	}()
+		return
+	}
+
+	migrator, err := migrations.NewMigrator(writer.DB)
+	if err != nil {
+		logs.Fatal("Error loading migrations", err)
+	}
+
+	if config.Database.AutoMigrate {
+		count, err := migrator.Up(context.Background())
+		if err != nil {
+			logs.Fatal("Error applying migrations", err)
+		}
+		logs.Info("Database schema is up to date", zap.Int("applied", count))
+	}
+
+	reader, err := configs.GetReaderSqlx(config.Database, writer)
+	if err != nil {
+		logs.Fatal("Error connecting to the read replicas", err)
+	}
+
+	shutdownTracing, err := configs.SetupTracing(config.Tracing)
+	if err != nil {
+		logs.Fatal("Error setting up tracing", err)
+	}
 
 	// Initialize the Fiber application
 	app := fiber.New(fiber.Config{
 		AppName:      "Ticket-Booking",
 		ServerHeader: "Fiber",
+		ReadTimeout:  config.Server.ReadTimeout,
+		WriteTimeout: config.Server.WriteTimeout,
+		IdleTimeout:  config.Server.IdleTimeout,
 	})
+	server := configs.NewServer(app, config.Server.ShutdownTimeout)
 
-	tokenization := services.NewTokenization()
-	cryptography := services.NewCryptography()
+	// Close the connections once the requests have drained and the background workers stopped
+	server.OnShutdown("reader", reader.Close)
+	server.OnShutdown("writer", writer.Close)
+	server.OnShutdown("tracing", func() error {
+		// Flush the spans of the last requests
+		ctx, cancel := context.WithTimeout(context.Background(), config.Server.ShutdownTimeout)
+		defer cancel()
+		return shutdownTracing(ctx)
+	})
+
+	// Register the probes and metrics first, so they answer even while the database circuit breaker is open
+	handlers.NewHealthHandler(app, services.NewHealth(writer, reader, migrator, server))
+	handlers.NewMetricsHandler(app)
+	metrics.RegisterDatabase(writer.Name(), writer.DB.DB)
+	for _, pool := range reader.Pools() {
+		metrics.RegisterDatabase(pool.Name(), pool.DB.DB)
+	}
+
+	app.Use(middlewares.RequestID())
+	app.Use(middlewares.Tracing())
+	app.Use(middlewares.Metrics())
+	app.Use(middlewares.DatabaseAvailable(writer))
+	app.Use(middlewares.ReadYourWrites(reader))
 
 	// Initialize repositories
 	eventRepo := repositories.NewEventRepository(reader, writer)
 	ticketRepo := repositories.NewTicketRepository(reader, writer)
 	authRepo := repositories.NewAccountRepository(reader, writer)
+	sessionRepo := repositories.NewSessionRepository(reader, writer)
+	revocationRepo := repositories.NewRevocationRepository(reader, writer)
+	twoFactorRepo := repositories.NewTwoFactorRepository(reader, writer)
+	accountTokenRepo := repositories.NewAccountTokenRepository(reader, writer)
+	apiKeyRepo := repositories.NewAPIKeyRepository(reader, writer)
+	oidcRepo := repositories.NewOIDCRepository(reader, writer)
+	passkeyRepo := repositories.NewPasskeyRepository(reader, writer)
+
+	tokenization, err := services.NewTokenization(sessionRepo, revocationRepo, config.JWT)
+	if err != nil {
+		logs.Fatal("Error initializing tokenization", err)
+	}
+	cryptography := services.NewCryptography(config.Argon2)
+	totp := services.NewTOTP(config.TOTP.Issuer)
+	accountTokens := services.NewAccountTokens(tokenization, accountTokenRepo)
+	accountMails := services.NewAccountMails(services.NewMailer(config.Mailer), config.App.BaseURL)
+	signInGuard := services.NewSignInGuard(authRepo, config.SignIn)
+	apiKeys := services.NewAPIKeys(apiKeyRepo)
+	passkeys, err := services.NewPasskeys(passkeyRepo, config.WebAuthn, config.App.BaseURL)
+	if err != nil {
+		logs.Fatal("Error initializing passkeys", err)
+	}
+	openIDConnect := services.NewOIDC(oidcRepo, &http.Client{Timeout: 10 * time.Second}, config.OIDC)
+	jobs := services.NewJobs(100)
+
+	// Ping the databases so their circuit breakers close again as soon as they are back
+	server.Go("writer health check", func(ctx context.Context) {
+		writer.RunHealthCheck(ctx, config.Database.HealthCheckInterval)
+	})
+	server.Go("reader health check", func(ctx context.Context) {
+		reader.RunHealthCheck(ctx, config.Database.HealthCheckInterval)
+	})
+
+	// Send the emails of the account flows off the request path
+	server.Go("background jobs", jobs.Run)
+
+	if config.Log.RotateInterval > 0 {
+		server.Go("log rotation", func(ctx context.Context) { logs.RunRotation(ctx, config.Log.RotateInterval) })
+	}
+
+	// Periodically remove expired refresh tokens, revocations, account tokens, sign-in throttles, OIDC states and passkey ceremonies
+	server.Go("tokenization cleanup", func(ctx context.Context) { tokenization.RunCleanup(ctx, time.Hour) })
+	server.Go("account tokens cleanup", func(ctx context.Context) { accountTokens.RunCleanup(ctx, time.Hour) })
+	server.Go("sign-in guard cleanup", func(ctx context.Context) { signInGuard.RunCleanup(ctx, time.Minute) })
+	server.Go("OIDC cleanup", func(ctx context.Context) { openIDConnect.RunCleanup(ctx, time.Hour) })
+	server.Go("passkeys cleanup", func(ctx context.Context) { passkeys.RunCleanup(ctx, time.Hour) })
 
 	// Set up handlers
-	handlers.NewEventHandler(app, eventRepo, tokenization)
-	handlers.NewTicketHandler(app, ticketRepo, eventRepo, tokenization)
-	handlers.NewAuthHandler(app, authRepo, tokenization, cryptography)
+	handlers.NewEventHandler(app, eventRepo, tokenization, apiKeys)
+	handlers.NewTicketHandler(app, ticketRepo, eventRepo, authRepo, tokenization, apiKeys)
+	if _, err := handlers.NewAuthHandler(app, authRepo, tokenization, cryptography, accountTokens, accountMails, signInGuard, jobs); err != nil {
+		logs.Fatal("Error initializing the auth handler", err)
+	}
+	handlers.NewProfileHandler(app, authRepo, tokenization, cryptography, accountTokens, accountMails, signInGuard)
+	handlers.NewOIDCHandler(app, authRepo, oidcRepo, openIDConnect, tokenization, cryptography)
+	handlers.NewPasskeyHandler(app, authRepo, twoFactorRepo, passkeys, tokenization, cryptography, totp, signInGuard)
+	handlers.NewTwoFactorHandler(app, authRepo, twoFactorRepo, tokenization, totp, signInGuard)
+	handlers.NewAdminHandler(app, authRepo, tokenization)
+	handlers.NewAPIKeyHandler(app, apiKeys, tokenization)
+	handlers.NewJWKSHandler(app, tokenization)
+
+	// Shut down gracefully on SIGTERM, sent by orchestrators, and SIGINT (Ctrl+C)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
 
-	port := ":3000"
+	port := fmt.Sprintf(":%d", config.Server.Port)
 	logs.Info("Starting server on port", zap.String("port", port))
-	if err := app.Listen(port); err != nil {
-		logs.Fatal("Error starting server", err)
+	if err := server.Run(ctx, port); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 }
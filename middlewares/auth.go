@@ -2,34 +2,99 @@ package middlewares
 
 import (
 	"strings"
+	"ticket-booking/configs"
 	"ticket-booking/configs/errs"
 	"ticket-booking/configs/logs"
+	"ticket-booking/entities"
 	"ticket-booking/services"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func Auth(tokenization services.Tokenization) fiber.Handler {
+// principalKey is the ctx.Locals key holding the authenticated *entities.Principal.
+const principalKey = "principal"
+
+// Auth authenticates the caller with a bearer access token. When apiKeys is not nil, an API key is
+// accepted instead, either as the bearer token or in the X-API-Key header; routes managing the account
+// itself pass nil so they stay reserved to signed-in sessions.
+func Auth(tokenization services.Tokenization, apiKeys services.APIKeys) fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
 		authHeader := ctx.Get("Authorization")
+		if apiKeys != nil {
+			if key := apiKeyCredential(ctx, authHeader); key != "" {
+				return authenticateAPIKey(ctx, apiKeys, key)
+			}
+		}
+
 		if authHeader == "" {
-			logs.Error("Middleware.Auth: Missing Authorization header", nil)
+			logs.ErrorContext(ctx.UserContext(), "Middleware.Auth: Missing Authorization header", nil)
 			return errs.NewUnauthorized(ctx, "Missing Authorization header")
 		}
 
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-			logs.Error("Middleware.Auth: Invalid Authorization header format", nil)
+			logs.ErrorContext(ctx.UserContext(), "Middleware.Auth: Invalid Authorization header format", nil)
 			return errs.NewUnauthorized(ctx, "Invalid Authorization header format")
 		}
 
 		token := strings.TrimPrefix(authHeader, "Bearer ")
 
-		valid, err := tokenization.ValidateToken(token)
-		if err != nil || !valid {
-			logs.Error("Middleware.Auth: Invalid or expired token", err)
+		principal, err := tokenization.ParseToken(token)
+		if err != nil {
+			logs.ErrorContext(ctx.UserContext(), "Middleware.Auth: Invalid or expired token", err)
 			return errs.NewUnauthorized(ctx, "Invalid or expired token")
 		}
 
+		revoked, err := tokenization.IsRevoked(ctx.UserContext(), principal)
+		if err != nil {
+			logs.ErrorContext(ctx.UserContext(), "Middleware.Auth: Failed to check token revocation", err)
+			return errs.NewUnauthorized(ctx, "Invalid or expired token")
+		}
+
+		if revoked {
+			logs.WarnContext(ctx.UserContext(), "Middleware.Auth: Revoked token")
+			return errs.NewUnauthorized(ctx, "Invalid or expired token")
+		}
+
+		setPrincipal(ctx, principal)
+
 		return ctx.Next()
 	}
 }
+
+// apiKeyCredential returns the API key presented by the request, if any.
+func apiKeyCredential(ctx *fiber.Ctx, authHeader string) string {
+	if key := ctx.Get("X-API-Key"); key != "" {
+		return key
+	}
+
+	if token := strings.TrimPrefix(authHeader, "Bearer "); strings.HasPrefix(token, services.APIKeyPrefix) {
+		return token
+	}
+
+	return ""
+}
+
+func authenticateAPIKey(ctx *fiber.Ctx, apiKeys services.APIKeys, key string) error {
+	principal, err := apiKeys.Authenticate(ctx.UserContext(), key)
+	if err != nil {
+		logs.ErrorContext(ctx.UserContext(), "Middleware.Auth: Invalid API key", err)
+		return errs.NewUnauthorized(ctx, "Invalid or expired API key")
+	}
+
+	setPrincipal(ctx, principal)
+
+	return ctx.Next()
+}
+
+// setPrincipal stores the principal for the handlers and tags the request context with its account,
+// so the repositories can route its reads after its own writes.
+func setPrincipal(ctx *fiber.Ctx, principal *entities.Principal) {
+	ctx.Locals(principalKey, principal)
+	ctx.SetUserContext(configs.WithAccount(ctx.UserContext(), principal.AccountID))
+}
+
+// GetPrincipal returns the caller authenticated by Auth, or nil on routes without it.
+func GetPrincipal(ctx *fiber.Ctx) *entities.Principal {
+	principal, _ := ctx.Locals(principalKey).(*entities.Principal)
+	return principal
+}
@@ -0,0 +1,89 @@
+package middlewares
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ticket-booking/configs"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The write marker carries the time of the client's last write, in Unix milliseconds, so any instance
+// can route its next reads to the primary. Browsers return the cookie; other clients echo the header.
+const (
+	WriteMarkerCookie = "last_write"
+	WriteMarkerHeader = "X-Last-Write"
+)
+
+// ReadYourWrites keeps the callers of write requests from reading stale data off the replicas: reads
+// made while handling the request go to the primary, and so do the reads of the caller for the
+// configured window once the request succeeded. The window is tracked per account by the instance
+// that served the write, and by the client through the write marker, which reaches every instance.
+func ReadYourWrites(replicas *configs.Replicas) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			if wroteRecently(c, replicas.Window()) {
+				c.SetUserContext(configs.WithPrimaryReads(c.UserContext()))
+			}
+			return c.Next()
+		}
+
+		c.SetUserContext(configs.WithPrimaryReads(c.UserContext()))
+
+		err := c.Next()
+
+		if err == nil && c.Response().StatusCode() < http.StatusBadRequest {
+			if principal := GetPrincipal(c); principal != nil {
+				replicas.RecordWrite(principal.AccountID)
+			}
+			setWriteMarker(c, replicas.Window())
+		}
+
+		return err
+	}
+}
+
+// wroteRecently reports whether the client's write marker falls within the window, either way to allow
+// for clock skew between instances. The marker is not signed: forging it only sends the client's own
+// reads to the primary.
+func wroteRecently(c *fiber.Ctx, window time.Duration) bool {
+	if window <= 0 {
+		return false
+	}
+
+	marker := c.Get(WriteMarkerHeader)
+	if marker == "" {
+		marker = c.Cookies(WriteMarkerCookie)
+	}
+
+	wroteAt, err := strconv.ParseInt(marker, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	elapsed := time.Since(time.UnixMilli(wroteAt))
+	return elapsed.Abs() <= window
+}
+
+// setWriteMarker hands the time of the write to the client, for as long as the window lasts.
+func setWriteMarker(c *fiber.Ctx, window time.Duration) {
+	if window <= 0 {
+		return
+	}
+
+	marker := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	c.Set(WriteMarkerHeader, marker)
+	c.Cookie(&fiber.Cookie{
+		Name:     WriteMarkerCookie,
+		Value:    marker,
+		Path:     "/",
+		MaxAge:   int(math.Ceil(window.Seconds())),
+		Secure:   c.Protocol() == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
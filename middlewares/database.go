@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+
+	"ticket-booking/configs"
+	"ticket-booking/configs/errs"
+	"ticket-booking/configs/logs"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DatabaseAvailable fails fast with 503 Service Unavailable while the circuit breaker of the primary
+// database is open, instead of letting every request wait for a timeout. Requests that hit the
+// breaker while being handled get the same answer rather than a 500.
+func DatabaseAvailable(db *configs.Database) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !db.Available() {
+			return serviceUnavailable(c, db)
+		}
+
+		err := c.Next()
+
+		if c.Response().StatusCode() == http.StatusInternalServerError && !db.Available() {
+			return serviceUnavailable(c, db)
+		}
+
+		return err
+	}
+}
+
+func serviceUnavailable(c *fiber.Ctx, db *configs.Database) error {
+	logs.WarnContext(c.UserContext(), "Middleware.DatabaseAvailable: Database unavailable, rejecting request")
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(db.RetryAfter().Seconds())+1))
+	return errs.NewServiceUnavailable(c, "Service temporarily unavailable, please retry later")
+}
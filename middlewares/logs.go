@@ -15,7 +15,7 @@ func Logger() fiber.Handler {
 		err := c.Next()
 
 		duration := time.Since(start)
-		logs.Request(c.Method(), c.Path(), c.Response().StatusCode(), duration.String())
+		logs.Request(c.Method(), c.Path(), c.Response().StatusCode(), duration.String(), logs.ContextFields(c.UserContext())...)
 
 		return err
 	}
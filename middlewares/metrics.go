@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"ticket-booking/configs/metrics"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// unmatchedRoute labels requests that no route handled, e.g. 404s or requests rejected by a global middleware.
+const unmatchedRoute = "unmatched"
+
+// Metrics records the count and latency of every request that passes through it.
+func Metrics() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		err := c.Next()
+
+		status := strconv.Itoa(responseStatus(c, err))
+		route := routeTemplate(c)
+		metrics.HTTPRequests.WithLabelValues(c.Method(), route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(c.Method(), route, status).Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
+
+// responseStatus returns the status the client gets. An error is only turned into a response by the
+// error handler once it has gone back through every middleware, so its status is worked out the way
+// the default handler does instead: the code of a *fiber.Error, 500 otherwise.
+func responseStatus(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code
+	}
+
+	return fiber.StatusInternalServerError
+}
+
+// routeTemplate returns the path the matched route was registered with. Global middlewares are
+// registered on "/", which no route of the application uses, so that path means no route matched.
+func routeTemplate(c *fiber.Ctx) string {
+	if path := c.Route().Path; path != "/" {
+		return path
+	}
+
+	return unmatchedRoute
+}
@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"ticket-booking/configs/logs"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// maxRequestIDLength bounds the IDs accepted from callers, since they end up in every log entry.
+const maxRequestIDLength = 128
+
+// RequestID tags the request with the X-Request-ID sent by the caller, e.g. a load balancer, or a new
+// one. The ID goes into the request context, for the logs, and back in the response headers.
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestID := c.Get(fiber.HeaderXRequestID)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.Set(fiber.HeaderXRequestID, requestID)
+		c.SetUserContext(logs.WithRequestID(c.UserContext(), requestID))
+
+		return c.Next()
+	}
+}
+
+// validRequestID accepts short IDs of printable ASCII characters, so a caller cannot inject
+// whitespace or control characters into the logs.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, char := range requestID {
+		if char <= ' ' || char > '~' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"ticket-booking/configs/errs"
+	"ticket-booking/configs/logs"
+	"ticket-booking/entities"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireRole allows the request through only when the caller holds one of the given roles.
+// Admins are always allowed. It must run after Auth.
+func RequireRole(roles ...entities.Role) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		principal := GetPrincipal(ctx)
+		if principal == nil {
+			logs.ErrorContext(ctx.UserContext(), "Middleware.RequireRole: Missing principal", nil)
+			return errs.NewUnauthorized(ctx, "Invalid or expired token")
+		}
+
+		if !principal.HasRole(roles...) {
+			logs.WarnContext(ctx.UserContext(), "Middleware.RequireRole: Access denied")
+			return errs.NewForbidden(ctx, "Insufficient permissions")
+		}
+
+		return ctx.Next()
+	}
+}
+
+// RequirePermission allows the request through only when the caller's scopes include the permission.
+// It must run after Auth.
+func RequirePermission(permission entities.Permission) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		principal := GetPrincipal(ctx)
+		if principal == nil {
+			logs.ErrorContext(ctx.UserContext(), "Middleware.RequirePermission: Missing principal", nil)
+			return errs.NewUnauthorized(ctx, "Invalid or expired token")
+		}
+
+		if !principal.HasScope(permission) {
+			logs.WarnContext(ctx.UserContext(), "Middleware.RequirePermission: Access denied")
+			return errs.NewForbidden(ctx, "Insufficient permissions")
+		}
+
+		return ctx.Next()
+	}
+}
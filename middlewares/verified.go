@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"context"
+	"time"
+
+	"ticket-booking/configs/errs"
+	"ticket-booking/configs/logs"
+	"ticket-booking/repositories"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireVerifiedEmail allows the request through only when the caller verified their email address.
+// The account is read on every request so a verification applies without signing in again. It must run after Auth.
+func RequireVerifiedEmail(accountRepo repositories.AccountRepository) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		principal := GetPrincipal(ctx)
+		if principal == nil {
+			logs.ErrorContext(ctx.UserContext(), "Middleware.RequireVerifiedEmail: Missing principal", nil)
+			return errs.NewUnauthorized(ctx, "Invalid or expired token")
+		}
+
+		context, cancel := context.WithTimeout(ctx.UserContext(), 5*time.Second)
+		defer cancel()
+
+		account, err := accountRepo.FindByID(context, principal.AccountID)
+		if err != nil {
+			logs.ErrorContext(ctx.UserContext(), "Middleware.RequireVerifiedEmail: Failed to retrieve account", err)
+			return errs.NewInternalServerError(ctx, "Failed to retrieve account")
+		}
+
+		if !account.IsEmailVerified() {
+			logs.WarnContext(ctx.UserContext(), "Middleware.RequireVerifiedEmail: Email not verified")
+			return errs.NewForbidden(ctx, "Email address not verified")
+		}
+
+		return ctx.Next()
+	}
+}
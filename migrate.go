@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"ticket-booking/migrations"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const migrateUsage = `usage: ticket-booking [flags] migrate <command>
+
+commands:
+  up              apply every pending migration
+  down [steps]    revert the last steps migrations (default 1)
+  status          list the migrations and whether they are applied
+  force <version> record the migrations up to version as applied without running them,
+                  for databases created from tables.sql`
+
+// runMigrate runs the "migrate" subcommand against the primary database.
+func runMigrate(db *sqlx.DB, args []string) error {
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	switch args[0] {
+	case "up":
+		count, err := migrator.Up(ctx)
+		fmt.Printf("Applied %d migration(s)\n", count)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		count, err := migrator.Down(ctx, steps)
+		fmt.Printf("Reverted %d migration(s)\n", count)
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return printMigrationStatus(statuses)
+	case "force":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		return migrator.Force(ctx, version)
+	default:
+		return errors.New(migrateUsage)
+	}
+}
+
+func printMigrationStatus(statuses []*migrations.Status) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+	for _, status := range statuses {
+		state := "pending"
+		switch {
+		case status.Missing:
+			state = "applied " + status.AppliedAt.Format(time.RFC3339) + ", file missing"
+		case status.Modified:
+			state = "applied " + status.AppliedAt.Format(time.RFC3339) + ", MODIFIED since"
+		case status.AppliedAt != nil:
+			state = "applied " + status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%03d\t%s\t%s\n", status.Version, status.Name, state)
+	}
+
+	return w.Flush()
+}
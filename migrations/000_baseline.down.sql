@@ -0,0 +1,3 @@
+DROP TABLE tickets;
+DROP TABLE events;
+DROP TABLE accounts;
@@ -0,0 +1,2 @@
+ALTER TABLE tickets DROP COLUMN public_id;
+ALTER TABLE events DROP COLUMN public_id;
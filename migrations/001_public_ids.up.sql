@@ -0,0 +1,28 @@
+-- Adds opaque, time-sortable public identifiers to events and tickets.
+-- The SERIAL keys stay in place for joins, only public_id is exposed through the API.
+-- Existing rows are backfilled with a UUIDv7 derived from created_at, so they keep their order.
+
+ALTER TABLE events ADD COLUMN public_id UUID;
+ALTER TABLE tickets ADD COLUMN public_id UUID;
+
+UPDATE events
+SET public_id = (
+    lpad(to_hex((extract(epoch FROM created_at) * 1000)::BIGINT), 12, '0')
+    || '7' || substr(md5(random()::TEXT), 1, 3)
+    || to_hex(8 + floor(random() * 4)::INT) || substr(md5(random()::TEXT), 1, 15)
+)::UUID
+WHERE public_id IS NULL;
+
+UPDATE tickets
+SET public_id = (
+    lpad(to_hex((extract(epoch FROM created_at) * 1000)::BIGINT), 12, '0')
+    || '7' || substr(md5(random()::TEXT), 1, 3)
+    || to_hex(8 + floor(random() * 4)::INT) || substr(md5(random()::TEXT), 1, 15)
+)::UUID
+WHERE public_id IS NULL;
+
+ALTER TABLE events ALTER COLUMN public_id SET NOT NULL;
+ALTER TABLE tickets ALTER COLUMN public_id SET NOT NULL;
+
+ALTER TABLE events ADD CONSTRAINT events_public_id_key UNIQUE (public_id);
+ALTER TABLE tickets ADD CONSTRAINT tickets_public_id_key UNIQUE (public_id);
@@ -0,0 +1,5 @@
+-- Adds roles to accounts. Existing accounts become customers.
+-- Promote the first administrator by hand, e.g.:
+--   UPDATE accounts SET roles = '{admin}' WHERE email = 'admin@example.com';
+
+ALTER TABLE accounts ADD COLUMN roles TEXT[] NOT NULL DEFAULT '{customer}';
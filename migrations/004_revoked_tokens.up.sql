@@ -0,0 +1,6 @@
+-- Holds the IDs (jti) of access tokens revoked by a logout until they would have expired anyway.
+
+CREATE TABLE revoked_tokens (
+    jti UUID PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,5 @@
+DROP TABLE recovery_codes;
+
+ALTER TABLE accounts DROP COLUMN totp_last_step;
+ALTER TABLE accounts DROP COLUMN totp_enabled;
+ALTER TABLE accounts DROP COLUMN totp_secret;
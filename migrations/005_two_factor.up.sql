@@ -0,0 +1,15 @@
+-- Adds TOTP two-factor authentication and its single-use recovery codes.
+
+ALTER TABLE accounts ADD COLUMN totp_secret VARCHAR(64);
+ALTER TABLE accounts ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE accounts ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE recovery_codes (
+    id UUID PRIMARY KEY,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX recovery_codes_account_id_idx ON recovery_codes (account_id);
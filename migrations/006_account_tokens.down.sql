@@ -0,0 +1,3 @@
+DROP TABLE account_tokens;
+
+ALTER TABLE accounts DROP COLUMN email_verified_at;
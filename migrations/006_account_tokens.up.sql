@@ -0,0 +1,16 @@
+-- Adds email verification and the single-use tokens sent by email (verification, password reset).
+-- Accounts created before this migration are considered verified, so existing customers can keep buying tickets.
+
+ALTER TABLE accounts ADD COLUMN email_verified_at TIMESTAMP;
+UPDATE accounts SET email_verified_at = created_at;
+
+CREATE TABLE account_tokens (
+    id UUID PRIMARY KEY,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    purpose VARCHAR(32) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX account_tokens_expires_at_idx ON account_tokens (expires_at);
@@ -0,0 +1,3 @@
+-- Anonymised accounts stay anonymised, they just look like regular accounts again.
+
+ALTER TABLE accounts DROP COLUMN deleted_at;
@@ -0,0 +1,4 @@
+-- Adds self-service account deletion. Deleted accounts are anonymised rather than removed,
+-- so their tickets keep pointing at a row for accounting.
+
+ALTER TABLE accounts ADD COLUMN deleted_at TIMESTAMP;
@@ -0,0 +1,4 @@
+DROP INDEX accounts_locked_until_idx;
+
+ALTER TABLE accounts DROP COLUMN locked_until;
+ALTER TABLE accounts DROP COLUMN failed_sign_ins;
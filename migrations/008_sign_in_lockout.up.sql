@@ -0,0 +1,6 @@
+-- Tracks failed sign-ins per account so repeated password guesses lock the account for a while.
+
+ALTER TABLE accounts ADD COLUMN failed_sign_ins INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD COLUMN locked_until TIMESTAMP;
+
+CREATE INDEX accounts_locked_until_idx ON accounts (locked_until) WHERE locked_until IS NOT NULL;
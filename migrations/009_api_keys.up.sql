@@ -0,0 +1,17 @@
+-- Adds account-owned API keys for scanners, reporting jobs and partner integrations.
+-- Only the SHA-256 hash of a key is stored; the prefix identifies it in listings.
+
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX api_keys_account_id_idx ON api_keys (account_id);
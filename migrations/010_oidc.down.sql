@@ -0,0 +1,2 @@
+DROP TABLE account_identities;
+DROP TABLE oidc_states;
@@ -0,0 +1 @@
+DROP INDEX account_tokens_account_id_idx;
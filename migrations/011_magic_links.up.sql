@@ -0,0 +1,4 @@
+-- Magic sign-in links are account tokens with the "magic_link" purpose.
+-- Requests are rate limited per account by counting its recent tokens.
+
+CREATE INDEX account_tokens_account_id_idx ON account_tokens (account_id, purpose, created_at);
@@ -0,0 +1,30 @@
+-- Adds WebAuthn passkeys. passkey_ceremonies holds the challenge of a pending registration or login.
+
+CREATE TABLE passkeys (
+    id UUID PRIMARY KEY,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    credential_id BYTEA NOT NULL UNIQUE,
+    public_key BYTEA NOT NULL,
+    attestation_type VARCHAR(32) NOT NULL,
+    aaguid BYTEA,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    transports TEXT[] NOT NULL DEFAULT '{}',
+    backup_eligible BOOLEAN NOT NULL DEFAULT FALSE,
+    backup_state BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP
+);
+
+CREATE INDEX passkeys_account_id_idx ON passkeys (account_id);
+
+CREATE TABLE passkey_ceremonies (
+    id UUID PRIMARY KEY,
+    account_id UUID REFERENCES accounts(id) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL,
+    session_data JSONB NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX passkey_ceremonies_expires_at_idx ON passkey_ceremonies (expires_at);
@@ -0,0 +1,6 @@
+DROP INDEX passkey_ceremonies_account_id_idx;
+DROP INDEX revoked_tokens_expires_at_idx;
+DROP INDEX refresh_tokens_session_id_idx;
+DROP INDEX sessions_account_id_idx;
+DROP INDEX tickets_account_id_idx;
+DROP INDEX tickets_event_id_idx;
@@ -0,0 +1,8 @@
+-- Indexes the foreign keys and the columns the repositories filter on, which PostgreSQL does not index by itself.
+
+CREATE INDEX tickets_event_id_idx ON tickets (event_id);
+CREATE INDEX tickets_account_id_idx ON tickets (account_id);
+CREATE INDEX sessions_account_id_idx ON sessions (account_id);
+CREATE INDEX refresh_tokens_session_id_idx ON refresh_tokens (session_id);
+CREATE INDEX revoked_tokens_expires_at_idx ON revoked_tokens (expires_at);
+CREATE INDEX passkey_ceremonies_account_id_idx ON passkey_ceremonies (account_id);
@@ -0,0 +1,5 @@
+-- Emails are stored lowercased so they match case-insensitively. Accounts registered with the same
+-- address in different cases must be merged by hand before this migration can be applied.
+
+UPDATE accounts SET email = lower(email) WHERE email <> lower(email);
+CREATE UNIQUE INDEX accounts_email_lower_idx ON accounts (lower(email));
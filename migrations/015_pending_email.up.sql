@@ -0,0 +1,3 @@
+-- A new email address is kept aside until the link sent to it is followed.
+
+ALTER TABLE accounts ADD COLUMN pending_email VARCHAR(255);
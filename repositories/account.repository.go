@@ -2,30 +2,49 @@ package repositories
 
 import (
 	"context"
+	"ticket-booking/configs"
 	"ticket-booking/configs/logs"
 	"ticket-booking/entities"
+	"time"
 
-	"github.com/jmoiron/sqlx"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type AccountRepository interface {
 	SignUp(ctx context.Context, auth *entities.Account) error
 	FindByEmail(ctx context.Context, email string) (*entities.Account, error)
+	FindByID(ctx context.Context, id uuid.UUID) (*entities.Account, error)
+	FindAll(ctx context.Context) ([]*entities.Account, error)
+	UpdatePassword(ctx context.Context, id uuid.UUID, password string) error
+	UpdateRoles(ctx context.Context, id uuid.UUID, roles []string) error
+	MarkEmailVerified(ctx context.Context, id uuid.UUID) error
+	UpdateProfile(ctx context.Context, account *entities.Account) error
+	SetPendingEmail(ctx context.Context, id uuid.UUID, email string) error
+	ConfirmPendingEmail(ctx context.Context, id uuid.UUID) (bool, error)
+	Anonymize(ctx context.Context, id uuid.UUID) error
+	FindLocked(ctx context.Context) ([]*entities.Account, error)
+	RecordFailedSignIn(ctx context.Context, id uuid.UUID) (int, error)
+	Lock(ctx context.Context, id uuid.UUID, until time.Time) error
+	ResetFailedSignIns(ctx context.Context, id uuid.UUID) error
 }
 
 type accountRepository struct {
-	reader *sqlx.DB
-	writer *sqlx.DB
+	reader configs.Reader
+	writer *configs.Database
 }
 
-func NewAccountRepository(reader, writer *sqlx.DB) AccountRepository {
+func NewAccountRepository(reader configs.Reader, writer *configs.Database) AccountRepository {
 	return &accountRepository{reader: reader, writer: writer}
 }
 
 func (r *accountRepository) SignUp(ctx context.Context, account *entities.Account) error {
-	query := `INSERT INTO accounts (id, name, email, password, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)`
-	if _, err := r.writer.ExecContext(ctx, query, account.ID, account.Name, account.Email, account.Password, account.CreatedAt, account.UpdatedAt); err != nil {
-		logs.Error("AuthRepository.SignUp: Failed to create auth", err)
+	ctx, span := configs.StartSpan(ctx, "AccountRepository.SignUp")
+	defer span.End()
+
+	query := `INSERT INTO accounts (id, name, email, password, roles, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	if _, err := r.writer.ExecContext(ctx, query, account.ID, account.Name, account.Email, account.Password, account.Roles, account.CreatedAt, account.UpdatedAt); err != nil {
+		logs.ErrorContext(ctx, "AuthRepository.SignUp: Failed to create auth", err)
 		return err
 	}
 
@@ -33,12 +52,257 @@ func (r *accountRepository) SignUp(ctx context.Context, account *entities.Accoun
 }
 
 func (r *accountRepository) FindByEmail(ctx context.Context, email string) (*entities.Account, error) {
+	ctx, span := configs.StartSpan(ctx, "AccountRepository.FindByEmail")
+	defer span.End()
+
 	auth := new(entities.Account)
-	query := `SELECT * FROM accounts WHERE email = $1`
-	if err := r.reader.GetContext(ctx, auth, query, email); err != nil {
-		logs.Error("authRepository.FindByEmail: Failed to retrieve auth by email", err)
+	query := `SELECT * FROM accounts WHERE email = $1 AND deleted_at IS NULL`
+	if err := r.reader.GetContext(ctx, auth, query, entities.NormalizeEmail(email)); err != nil {
+		logs.ErrorContext(ctx, "authRepository.FindByEmail: Failed to retrieve auth by email", err)
 		return nil, err
 	}
 
 	return auth, nil
 }
+
+func (r *accountRepository) FindByID(ctx context.Context, id uuid.UUID) (*entities.Account, error) {
+	ctx, span := configs.StartSpan(ctx, "AccountRepository.FindByID")
+	defer span.End()
+
+	account := new(entities.Account)
+	query := `SELECT * FROM accounts WHERE id = $1`
+	if err := r.reader.GetContext(ctx, account, query, id); err != nil {
+		logs.ErrorContext(ctx, "AccountRepository.FindByID: Failed to retrieve account by ID", err)
+		return nil, err
+	}
+
+	return account, nil
+}
+
+func (r *accountRepository) FindAll(ctx context.Context) ([]*entities.Account, error) {
+	ctx, span := configs.StartSpan(ctx, "AccountRepository.FindAll")
+	defer span.End()
+
+	var accounts []*entities.Account
+	query := `SELECT * FROM accounts ORDER BY created_at`
+	if err := r.reader.SelectContext(ctx, &accounts, query); err != nil {
+		logs.ErrorContext(ctx, "AccountRepository.FindAll: Failed to retrieve accounts", err)
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+func (r *accountRepository) UpdatePassword(ctx context.Context, id uuid.UUID, password string) error {
+	ctx, span := configs.StartSpan(ctx, "AccountRepository.UpdatePassword")
+	defer span.End()
+
+	query := `UPDATE accounts SET password = $1, updated_at = $2 WHERE id = $3`
+	if _, err := r.writer.ExecContext(ctx, query, password, time.Now(), id); err != nil {
+		logs.ErrorContext(ctx, "AccountRepository.UpdatePassword: Failed to update password", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *accountRepository) UpdateRoles(ctx context.Context, id uuid.UUID, roles []string) error {
+	ctx, span := configs.StartSpan(ctx, "AccountRepository.UpdateRoles")
+	defer span.End()
+
+	query := `UPDATE accounts SET roles = $1, updated_at = $2 WHERE id = $3`
+	if _, err := r.writer.ExecContext(ctx, query, pq.StringArray(roles), time.Now(), id); err != nil {
+		logs.ErrorContext(ctx, "AccountRepository.UpdateRoles: Failed to update roles", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *accountRepository) MarkEmailVerified(ctx context.Context, id uuid.UUID) error {
+	ctx, span := configs.StartSpan(ctx, "AccountRepository.MarkEmailVerified")
+	defer span.End()
+
+	query := `UPDATE accounts SET email_verified_at = $1, updated_at = $1 WHERE id = $2 AND email_verified_at IS NULL`
+	if _, err := r.writer.ExecContext(ctx, query, time.Now(), id); err != nil {
+		logs.ErrorContext(ctx, "AccountRepository.MarkEmailVerified: Failed to mark email as verified", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *accountRepository) UpdateProfile(ctx context.Context, account *entities.Account) error {
+	ctx, span := configs.StartSpan(ctx, "AccountRepository.UpdateProfile")
+	defer span.End()
+
+	query := `UPDATE accounts SET name = $1, updated_at = $2 WHERE id = $3`
+	if _, err := r.writer.ExecContext(ctx, query, account.Name, account.UpdatedAt, account.ID); err != nil {
+		logs.ErrorContext(ctx, "AccountRepository.UpdateProfile: Failed to update profile", err)
+		return err
+	}
+
+	return nil
+}
+
+// SetPendingEmail keeps the new address aside until it is confirmed. The links sent for an earlier
+// pending address are invalidated, so they cannot confirm this one.
+func (r *accountRepository) SetPendingEmail(ctx context.Context, id uuid.UUID, email string) error {
+	ctx, span := configs.StartSpan(ctx, "AccountRepository.SetPendingEmail")
+	defer span.End()
+
+	tx, err := r.writer.BeginTxx(ctx, nil)
+	if err != nil {
+		logs.ErrorContext(ctx, "AccountRepository.SetPendingEmail: Failed to begin transaction", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := `UPDATE accounts SET pending_email = $1, updated_at = $2 WHERE id = $3`
+	if _, err := tx.ExecContext(ctx, query, entities.NormalizeEmail(email), now, id); err != nil {
+		logs.ErrorContext(ctx, "AccountRepository.SetPendingEmail: Failed to set pending email", err)
+		return err
+	}
+
+	query = `UPDATE account_tokens SET used_at = $1 WHERE account_id = $2 AND purpose = $3 AND used_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, now, id, entities.TokenPurposeEmailChange); err != nil {
+		logs.ErrorContext(ctx, "AccountRepository.SetPendingEmail: Failed to invalidate email change tokens", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logs.ErrorContext(ctx, "AccountRepository.SetPendingEmail: Failed to commit transaction", err)
+		return err
+	}
+
+	return nil
+}
+
+// ConfirmPendingEmail swaps the pending address in as the verified email. It returns false when
+// there is no pending address or another account took it in the meantime.
+func (r *accountRepository) ConfirmPendingEmail(ctx context.Context, id uuid.UUID) (bool, error) {
+	ctx, span := configs.StartSpan(ctx, "AccountRepository.ConfirmPendingEmail")
+	defer span.End()
+
+	query := `UPDATE accounts SET email = pending_email, pending_email = NULL, email_verified_at = $1, updated_at = $1
+		WHERE id = $2 AND deleted_at IS NULL AND pending_email IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM accounts other WHERE other.email = accounts.pending_email)`
+	result, err := r.writer.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		logs.ErrorContext(ctx, "AccountRepository.ConfirmPendingEmail: Failed to confirm pending email", err)
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		logs.ErrorContext(ctx, "AccountRepository.ConfirmPendingEmail: Failed to read affected rows", err)
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// Anonymize erases the personal data of a deleted account. The row itself is kept
+// so tickets and orders still reference it for accounting.
+func (r *accountRepository) Anonymize(ctx context.Context, id uuid.UUID) error {
+	ctx, span := configs.StartSpan(ctx, "AccountRepository.Anonymize")
+	defer span.End()
+
+	tx, err := r.writer.BeginTxx(ctx, nil)
+	if err != nil {
+		logs.ErrorContext(ctx, "AccountRepository.Anonymize: Failed to begin transaction", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := `UPDATE accounts SET name = 'Deleted account', email = $1, email_verified_at = NULL, pending_email = NULL, password = '',
+		roles = '{}', totp_secret = NULL, totp_enabled = FALSE, totp_last_step = 0, updated_at = $2, deleted_at = $2
+		WHERE id = $3`
+	if _, err := tx.ExecContext(ctx, query, "deleted-"+id.String()+"@deleted.invalid", now, id); err != nil {
+		logs.ErrorContext(ctx, "AccountRepository.Anonymize: Failed to anonymize account", err)
+		return err
+	}
+
+	for _, query := range []string{
+		`DELETE FROM recovery_codes WHERE account_id = $1`,
+		`DELETE FROM account_tokens WHERE account_id = $1`,
+		`DELETE FROM account_identities WHERE account_id = $1`,
+		`DELETE FROM passkeys WHERE account_id = $1`,
+		`UPDATE sessions SET device = '', ip_address = '' WHERE account_id = $1`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			logs.ErrorContext(ctx, "AccountRepository.Anonymize: Failed to erase account data", err)
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE api_keys SET revoked_at = $1 WHERE account_id = $2 AND revoked_at IS NULL`, now, id); err != nil {
+		logs.ErrorContext(ctx, "AccountRepository.Anonymize: Failed to revoke API keys", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logs.ErrorContext(ctx, "AccountRepository.Anonymize: Failed to commit transaction", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *accountRepository) FindLocked(ctx context.Context) ([]*entities.Account, error) {
+	ctx, span := configs.StartSpan(ctx, "AccountRepository.FindLocked")
+	defer span.End()
+
+	var accounts []*entities.Account
+	query := `SELECT * FROM accounts WHERE locked_until > $1 ORDER BY locked_until DESC`
+	if err := r.reader.SelectContext(ctx, &accounts, query, time.Now()); err != nil {
+		logs.ErrorContext(ctx, "AccountRepository.FindLocked: Failed to retrieve locked accounts", err)
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+// RecordFailedSignIn increments the failed sign-in counter and returns its new value.
+func (r *accountRepository) RecordFailedSignIn(ctx context.Context, id uuid.UUID) (int, error) {
+	ctx, span := configs.StartSpan(ctx, "AccountRepository.RecordFailedSignIn")
+	defer span.End()
+
+	var failures int
+	query := `UPDATE accounts SET failed_sign_ins = failed_sign_ins + 1 WHERE id = $1 RETURNING failed_sign_ins`
+	if err := r.writer.GetContext(ctx, &failures, query, id); err != nil {
+		logs.ErrorContext(ctx, "AccountRepository.RecordFailedSignIn: Failed to record failed sign-in", err)
+		return 0, err
+	}
+
+	return failures, nil
+}
+
+func (r *accountRepository) Lock(ctx context.Context, id uuid.UUID, until time.Time) error {
+	ctx, span := configs.StartSpan(ctx, "AccountRepository.Lock")
+	defer span.End()
+
+	query := `UPDATE accounts SET locked_until = $1 WHERE id = $2`
+	if _, err := r.writer.ExecContext(ctx, query, until, id); err != nil {
+		logs.ErrorContext(ctx, "AccountRepository.Lock: Failed to lock account", err)
+		return err
+	}
+
+	return nil
+}
+
+// ResetFailedSignIns clears the failed sign-in counter and any lockout.
+func (r *accountRepository) ResetFailedSignIns(ctx context.Context, id uuid.UUID) error {
+	ctx, span := configs.StartSpan(ctx, "AccountRepository.ResetFailedSignIns")
+	defer span.End()
+
+	query := `UPDATE accounts SET failed_sign_ins = 0, locked_until = NULL WHERE id = $1`
+	if _, err := r.writer.ExecContext(ctx, query, id); err != nil {
+		logs.ErrorContext(ctx, "AccountRepository.ResetFailedSignIns: Failed to reset failed sign-ins", err)
+		return err
+	}
+
+	return nil
+}
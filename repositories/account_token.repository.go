@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"context"
+	"ticket-booking/configs"
+	"ticket-booking/configs/logs"
+	"ticket-booking/entities"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AccountTokenRepository interface {
+	Create(ctx context.Context, token *entities.AccountToken) error
+	Use(ctx context.Context, id uuid.UUID, purpose entities.TokenPurpose) (bool, error)
+	CreateWithinLimit(ctx context.Context, token *entities.AccountToken, since time.Time, max int) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type accountTokenRepository struct {
+	reader configs.Reader
+	writer *configs.Database
+}
+
+func NewAccountTokenRepository(reader configs.Reader, writer *configs.Database) AccountTokenRepository {
+	return &accountTokenRepository{reader: reader, writer: writer}
+}
+
+func (r *accountTokenRepository) Create(ctx context.Context, token *entities.AccountToken) error {
+	ctx, span := configs.StartSpan(ctx, "AccountTokenRepository.Create")
+	defer span.End()
+
+	query := `INSERT INTO account_tokens (id, account_id, purpose, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)`
+	if _, err := r.writer.ExecContext(ctx, query, token.ID, token.AccountID, token.Purpose, token.ExpiresAt, token.CreatedAt); err != nil {
+		logs.ErrorContext(ctx, "AccountTokenRepository.Create: Failed to create account token", err)
+		return err
+	}
+
+	return nil
+}
+
+// Use marks the token as used. It returns false when the token is unknown, expired or already used.
+func (r *accountTokenRepository) Use(ctx context.Context, id uuid.UUID, purpose entities.TokenPurpose) (bool, error) {
+	ctx, span := configs.StartSpan(ctx, "AccountTokenRepository.Use")
+	defer span.End()
+
+	query := `UPDATE account_tokens SET used_at = $1 WHERE id = $2 AND purpose = $3 AND used_at IS NULL AND expires_at > $1`
+	result, err := r.writer.ExecContext(ctx, query, time.Now(), id, purpose)
+	if err != nil {
+		logs.ErrorContext(ctx, "AccountTokenRepository.Use: Failed to use account token", err)
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		logs.ErrorContext(ctx, "AccountTokenRepository.Use: Failed to read affected rows", err)
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// CreateWithinLimit creates the token unless max tokens of its purpose were issued to the account since
+// the given time, and reports whether it did. The count and the insert run under a transaction-level
+// advisory lock on the account and purpose, so concurrent requests cannot both pass the count.
+func (r *accountTokenRepository) CreateWithinLimit(ctx context.Context, token *entities.AccountToken, since time.Time, max int) (bool, error) {
+	ctx, span := configs.StartSpan(ctx, "AccountTokenRepository.CreateWithinLimit")
+	defer span.End()
+
+	tx, err := r.writer.BeginTxx(ctx, nil)
+	if err != nil {
+		logs.ErrorContext(ctx, "AccountTokenRepository.CreateWithinLimit: Failed to begin transaction", err)
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, token.AccountID.String()+":"+string(token.Purpose)); err != nil {
+		logs.ErrorContext(ctx, "AccountTokenRepository.CreateWithinLimit: Failed to lock account tokens", err)
+		return false, err
+	}
+
+	var count int
+	query := `SELECT COUNT(*) FROM account_tokens WHERE account_id = $1 AND purpose = $2 AND created_at > $3`
+	if err := tx.GetContext(ctx, &count, query, token.AccountID, token.Purpose, since); err != nil {
+		logs.ErrorContext(ctx, "AccountTokenRepository.CreateWithinLimit: Failed to count account tokens", err)
+		return false, err
+	}
+
+	if count >= max {
+		return false, nil
+	}
+
+	query = `INSERT INTO account_tokens (id, account_id, purpose, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)`
+	if _, err := tx.ExecContext(ctx, query, token.ID, token.AccountID, token.Purpose, token.ExpiresAt, token.CreatedAt); err != nil {
+		logs.ErrorContext(ctx, "AccountTokenRepository.CreateWithinLimit: Failed to create account token", err)
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logs.ErrorContext(ctx, "AccountTokenRepository.CreateWithinLimit: Failed to commit transaction", err)
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *accountTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := configs.StartSpan(ctx, "AccountTokenRepository.DeleteExpired")
+	defer span.End()
+
+	result, err := r.writer.ExecContext(ctx, `DELETE FROM account_tokens WHERE expires_at < $1`, before)
+	if err != nil {
+		logs.ErrorContext(ctx, "AccountTokenRepository.DeleteExpired: Failed to delete expired account tokens", err)
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
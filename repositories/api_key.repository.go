@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"context"
+	"ticket-booking/configs"
+	"ticket-booking/configs/logs"
+	"ticket-booking/entities"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *entities.APIKey) error
+	FindByHash(ctx context.Context, keyHash string) (*entities.APIKey, error)
+	FindByAccountID(ctx context.Context, accountID uuid.UUID) ([]*entities.APIKey, error)
+	Touch(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+	Revoke(ctx context.Context, accountID, id uuid.UUID) (bool, error)
+}
+
+type apiKeyRepository struct {
+	reader configs.Reader
+	writer *configs.Database
+}
+
+func NewAPIKeyRepository(reader configs.Reader, writer *configs.Database) APIKeyRepository {
+	return &apiKeyRepository{reader: reader, writer: writer}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *entities.APIKey) error {
+	ctx, span := configs.StartSpan(ctx, "ApiKeyRepository.Create")
+	defer span.End()
+
+	query := `INSERT INTO api_keys (id, account_id, name, prefix, key_hash, scopes, expires_at, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	if _, err := r.writer.ExecContext(ctx, query, key.ID, key.AccountID, key.Name, key.Prefix, key.KeyHash, key.Scopes, key.ExpiresAt, key.CreatedAt); err != nil {
+		logs.ErrorContext(ctx, "APIKeyRepository.Create: Failed to create API key", err)
+		return err
+	}
+
+	return nil
+}
+
+// FindByHash returns an unrevoked key of a live account, along with the roles of its owner.
+// It reads from the writer so a revocation takes effect at once.
+func (r *apiKeyRepository) FindByHash(ctx context.Context, keyHash string) (*entities.APIKey, error) {
+	ctx, span := configs.StartSpan(ctx, "ApiKeyRepository.FindByHash")
+	defer span.End()
+
+	key := new(entities.APIKey)
+	query := `SELECT k.*, a.roles AS owner_roles FROM api_keys k JOIN accounts a ON a.id = k.account_id
+		WHERE k.key_hash = $1 AND k.revoked_at IS NULL AND a.deleted_at IS NULL`
+	if err := r.writer.GetContext(ctx, key, query, keyHash); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (r *apiKeyRepository) FindByAccountID(ctx context.Context, accountID uuid.UUID) ([]*entities.APIKey, error) {
+	ctx, span := configs.StartSpan(ctx, "ApiKeyRepository.FindByAccountID")
+	defer span.End()
+
+	var keys []*entities.APIKey
+	query := `SELECT * FROM api_keys WHERE account_id = $1 AND revoked_at IS NULL ORDER BY created_at DESC`
+	if err := r.reader.SelectContext(ctx, &keys, query, accountID); err != nil {
+		logs.ErrorContext(ctx, "APIKeyRepository.FindByAccountID: Failed to retrieve API keys", err)
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Touch records the use of a key, at most once a minute so busy scanners don't write on every request.
+func (r *apiKeyRepository) Touch(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	ctx, span := configs.StartSpan(ctx, "ApiKeyRepository.Touch")
+	defer span.End()
+
+	query := `UPDATE api_keys SET last_used_at = $1 WHERE id = $2 AND (last_used_at IS NULL OR last_used_at < $3)`
+	if _, err := r.writer.ExecContext(ctx, query, usedAt, id, usedAt.Add(-time.Minute)); err != nil {
+		logs.ErrorContext(ctx, "APIKeyRepository.Touch: Failed to update last use", err)
+		return err
+	}
+
+	return nil
+}
+
+// Revoke revokes a key of the account. It returns false when the account has no such active key.
+func (r *apiKeyRepository) Revoke(ctx context.Context, accountID, id uuid.UUID) (bool, error) {
+	ctx, span := configs.StartSpan(ctx, "ApiKeyRepository.Revoke")
+	defer span.End()
+
+	query := `UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND account_id = $3 AND revoked_at IS NULL`
+	result, err := r.writer.ExecContext(ctx, query, time.Now(), id, accountID)
+	if err != nil {
+		logs.ErrorContext(ctx, "APIKeyRepository.Revoke: Failed to revoke API key", err)
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		logs.ErrorContext(ctx, "APIKeyRepository.Revoke: Failed to read affected rows", err)
+		return false, err
+	}
+
+	return rows == 1, nil
+}
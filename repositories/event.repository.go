@@ -3,34 +3,39 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"ticket-booking/configs"
 	"ticket-booking/configs/logs"
 	"ticket-booking/entities"
 
-	"github.com/jmoiron/sqlx"
+	"github.com/google/uuid"
 )
 
 type EventRepository interface {
 	FindAll(ctx context.Context) ([]*entities.Event, error)
 	FindByID(ctx context.Context, id uint64) (*entities.Event, error)
+	FindByPublicID(ctx context.Context, publicID uuid.UUID) (*entities.Event, error)
 	Create(ctx context.Context, event *entities.Event) error
 	Update(ctx context.Context, event *entities.Event) error
 	Delete(ctx context.Context, id uint64) error
 }
 
 type eventRepository struct {
-	reader *sqlx.DB
-	writer *sqlx.DB
+	reader configs.Reader
+	writer *configs.Database
 }
 
-func NewEventRepository(reader, writer *sqlx.DB) EventRepository {
+func NewEventRepository(reader configs.Reader, writer *configs.Database) EventRepository {
 	return &eventRepository{reader: reader, writer: writer}
 }
 
 func (r *eventRepository) FindAll(ctx context.Context) ([]*entities.Event, error) {
+	ctx, span := configs.StartSpan(ctx, "EventRepository.FindAll")
+	defer span.End()
+
 	var events []*entities.Event
 	query := `SELECT * FROM events`
 	if err := r.reader.SelectContext(ctx, &events, query); err != nil {
-		logs.Error("EventRepository.FindAll: Failed to retrieve events", err)
+		logs.ErrorContext(ctx, "EventRepository.FindAll: Failed to retrieve events", err)
 		return nil, err
 	}
 
@@ -38,14 +43,35 @@ func (r *eventRepository) FindAll(ctx context.Context) ([]*entities.Event, error
 }
 
 func (r *eventRepository) FindByID(ctx context.Context, id uint64) (*entities.Event, error) {
+	ctx, span := configs.StartSpan(ctx, "EventRepository.FindByID")
+	defer span.End()
+
 	event := new(entities.Event)
 	query := `SELECT * FROM events WHERE id = $1`
 	if err := r.reader.GetContext(ctx, event, query, id); err != nil {
 		if err == sql.ErrNoRows {
-			logs.Warn("EventRepository.FindByID: Event not found")
+			logs.WarnContext(ctx, "EventRepository.FindByID: Event not found")
 			return nil, nil
 		}
-		logs.Error("EventRepository.FindByID: Failed to retrieve event by ID", err)
+		logs.ErrorContext(ctx, "EventRepository.FindByID: Failed to retrieve event by ID", err)
+		return nil, err
+	}
+
+	return event, nil
+}
+
+func (r *eventRepository) FindByPublicID(ctx context.Context, publicID uuid.UUID) (*entities.Event, error) {
+	ctx, span := configs.StartSpan(ctx, "EventRepository.FindByPublicID")
+	defer span.End()
+
+	event := new(entities.Event)
+	query := `SELECT * FROM events WHERE public_id = $1`
+	if err := r.reader.GetContext(ctx, event, query, publicID); err != nil {
+		if err == sql.ErrNoRows {
+			logs.WarnContext(ctx, "EventRepository.FindByPublicID: Event not found")
+			return nil, err
+		}
+		logs.ErrorContext(ctx, "EventRepository.FindByPublicID: Failed to retrieve event by public ID", err)
 		return nil, err
 	}
 
@@ -53,9 +79,12 @@ func (r *eventRepository) FindByID(ctx context.Context, id uint64) (*entities.Ev
 }
 
 func (r *eventRepository) Create(ctx context.Context, event *entities.Event) error {
-	query := `INSERT INTO events (title, location, date, created_at, updated_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`
-	if err := r.writer.QueryRowContext(ctx, query, event.Title, event.Location, event.Date, event.CreatedAt, event.UpdatedAt).Scan(&event.ID); err != nil {
-		logs.Error("EventRepository.Create: Failed to create event", err)
+	ctx, span := configs.StartSpan(ctx, "EventRepository.Create")
+	defer span.End()
+
+	query := `INSERT INTO events (public_id, title, location, date, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	if err := r.writer.GetContext(ctx, &event.ID, query, event.PublicID, event.Title, event.Location, event.Date, event.CreatedAt, event.UpdatedAt); err != nil {
+		logs.ErrorContext(ctx, "EventRepository.Create: Failed to create event", err)
 		return err
 	}
 
@@ -63,9 +92,12 @@ func (r *eventRepository) Create(ctx context.Context, event *entities.Event) err
 }
 
 func (r *eventRepository) Update(ctx context.Context, event *entities.Event) error {
+	ctx, span := configs.StartSpan(ctx, "EventRepository.Update")
+	defer span.End()
+
 	query := `UPDATE events SET title = $1, location = $2, date = $3, updated_at = $4 WHERE id = $5`
 	if _, err := r.writer.ExecContext(ctx, query, event.Title, event.Location, event.Date, event.UpdatedAt, event.ID); err != nil {
-		logs.Error("EventRepository.Update: Failed to update event", err)
+		logs.ErrorContext(ctx, "EventRepository.Update: Failed to update event", err)
 		return err
 	}
 
@@ -73,9 +105,12 @@ func (r *eventRepository) Update(ctx context.Context, event *entities.Event) err
 }
 
 func (r *eventRepository) Delete(ctx context.Context, id uint64) error {
+	ctx, span := configs.StartSpan(ctx, "EventRepository.Delete")
+	defer span.End()
+
 	query := `DELETE FROM events WHERE id = $1`
 	if _, err := r.writer.ExecContext(ctx, query, id); err != nil {
-		logs.Error("EventRepository.Delete: Failed to delete event", err)
+		logs.ErrorContext(ctx, "EventRepository.Delete: Failed to delete event", err)
 		return err
 	}
 
@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"context"
+	"ticket-booking/configs"
+	"ticket-booking/configs/logs"
+	"ticket-booking/entities"
+	"time"
+)
+
+type OIDCRepository interface {
+	CreateState(ctx context.Context, state *entities.OIDCState) error
+	ConsumeState(ctx context.Context, state, provider string) (*entities.OIDCState, error)
+	DeleteExpiredStates(ctx context.Context, before time.Time) (int64, error)
+	FindIdentity(ctx context.Context, provider, subject string) (*entities.AccountIdentity, error)
+	CreateIdentity(ctx context.Context, identity *entities.AccountIdentity) error
+}
+
+type oidcRepository struct {
+	reader configs.Reader
+	writer *configs.Database
+}
+
+func NewOIDCRepository(reader configs.Reader, writer *configs.Database) OIDCRepository {
+	return &oidcRepository{reader: reader, writer: writer}
+}
+
+func (r *oidcRepository) CreateState(ctx context.Context, state *entities.OIDCState) error {
+	ctx, span := configs.StartSpan(ctx, "OidcRepository.CreateState")
+	defer span.End()
+
+	query := `INSERT INTO oidc_states (state, provider, nonce, code_verifier, expires_at, created_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := r.writer.ExecContext(ctx, query, state.State, state.Provider, state.Nonce, state.CodeVerifier, state.ExpiresAt, state.CreatedAt); err != nil {
+		logs.ErrorContext(ctx, "OIDCRepository.CreateState: Failed to create state", err)
+		return err
+	}
+
+	return nil
+}
+
+// ConsumeState deletes and returns an unexpired state of the provider, so each can complete a single sign-in.
+// It returns sql.ErrNoRows when the state is unknown, expired, already used or issued for another provider.
+func (r *oidcRepository) ConsumeState(ctx context.Context, state, provider string) (*entities.OIDCState, error) {
+	ctx, span := configs.StartSpan(ctx, "OidcRepository.ConsumeState")
+	defer span.End()
+
+	model := new(entities.OIDCState)
+	query := `DELETE FROM oidc_states WHERE state = $1 AND provider = $2 AND expires_at > $3 RETURNING *`
+	if err := r.writer.GetContext(ctx, model, query, state, provider, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return model, nil
+}
+
+func (r *oidcRepository) DeleteExpiredStates(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := configs.StartSpan(ctx, "OidcRepository.DeleteExpiredStates")
+	defer span.End()
+
+	result, err := r.writer.ExecContext(ctx, `DELETE FROM oidc_states WHERE expires_at < $1`, before)
+	if err != nil {
+		logs.ErrorContext(ctx, "OIDCRepository.DeleteExpiredStates: Failed to delete expired states", err)
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (r *oidcRepository) FindIdentity(ctx context.Context, provider, subject string) (*entities.AccountIdentity, error) {
+	ctx, span := configs.StartSpan(ctx, "OidcRepository.FindIdentity")
+	defer span.End()
+
+	identity := new(entities.AccountIdentity)
+	query := `SELECT * FROM account_identities WHERE provider = $1 AND subject = $2`
+	if err := r.reader.GetContext(ctx, identity, query, provider, subject); err != nil {
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+func (r *oidcRepository) CreateIdentity(ctx context.Context, identity *entities.AccountIdentity) error {
+	ctx, span := configs.StartSpan(ctx, "OidcRepository.CreateIdentity")
+	defer span.End()
+
+	query := `INSERT INTO account_identities (id, account_id, provider, subject, email, created_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := r.writer.ExecContext(ctx, query, identity.ID, identity.AccountID, identity.Provider, identity.Subject, identity.Email, identity.CreatedAt); err != nil {
+		logs.ErrorContext(ctx, "OIDCRepository.CreateIdentity: Failed to create identity", err)
+		return err
+	}
+
+	return nil
+}
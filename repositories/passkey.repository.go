@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"context"
+	"ticket-booking/configs"
+	"ticket-booking/configs/logs"
+	"ticket-booking/entities"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PasskeyRepository interface {
+	Create(ctx context.Context, passkey *entities.Passkey) error
+	FindByAccountID(ctx context.Context, accountID uuid.UUID) ([]*entities.Passkey, error)
+	UpdateSignCount(ctx context.Context, id uuid.UUID, signCount int64, backupState bool, usedAt time.Time) error
+	Delete(ctx context.Context, accountID, id uuid.UUID) (bool, error)
+	CreateCeremony(ctx context.Context, ceremony *entities.PasskeyCeremony) error
+	ConsumeCeremony(ctx context.Context, id uuid.UUID, kind entities.CeremonyKind) (*entities.PasskeyCeremony, error)
+	DeleteExpiredCeremonies(ctx context.Context, before time.Time) (int64, error)
+}
+
+type passkeyRepository struct {
+	reader configs.Reader
+	writer *configs.Database
+}
+
+func NewPasskeyRepository(reader configs.Reader, writer *configs.Database) PasskeyRepository {
+	return &passkeyRepository{reader: reader, writer: writer}
+}
+
+func (r *passkeyRepository) Create(ctx context.Context, passkey *entities.Passkey) error {
+	ctx, span := configs.StartSpan(ctx, "PasskeyRepository.Create")
+	defer span.End()
+
+	query := `INSERT INTO passkeys (id, account_id, name, credential_id, public_key, attestation_type, aaguid, sign_count, transports, backup_eligible, backup_state, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	if _, err := r.writer.ExecContext(ctx, query, passkey.ID, passkey.AccountID, passkey.Name, passkey.CredentialID, passkey.PublicKey, passkey.AttestationType,
+		passkey.AAGUID, passkey.SignCount, passkey.Transports, passkey.BackupEligible, passkey.BackupState, passkey.CreatedAt); err != nil {
+		logs.ErrorContext(ctx, "PasskeyRepository.Create: Failed to create passkey", err)
+		return err
+	}
+
+	return nil
+}
+
+// FindByAccountID reads from the writer, the sign counts must be current when verifying an assertion.
+func (r *passkeyRepository) FindByAccountID(ctx context.Context, accountID uuid.UUID) ([]*entities.Passkey, error) {
+	ctx, span := configs.StartSpan(ctx, "PasskeyRepository.FindByAccountID")
+	defer span.End()
+
+	var passkeys []*entities.Passkey
+	query := `SELECT * FROM passkeys WHERE account_id = $1 ORDER BY created_at`
+	if err := r.writer.SelectContext(ctx, &passkeys, query, accountID); err != nil {
+		logs.ErrorContext(ctx, "PasskeyRepository.FindByAccountID: Failed to retrieve passkeys", err)
+		return nil, err
+	}
+
+	return passkeys, nil
+}
+
+func (r *passkeyRepository) UpdateSignCount(ctx context.Context, id uuid.UUID, signCount int64, backupState bool, usedAt time.Time) error {
+	ctx, span := configs.StartSpan(ctx, "PasskeyRepository.UpdateSignCount")
+	defer span.End()
+
+	query := `UPDATE passkeys SET sign_count = $1, backup_state = $2, last_used_at = $3 WHERE id = $4`
+	if _, err := r.writer.ExecContext(ctx, query, signCount, backupState, usedAt, id); err != nil {
+		logs.ErrorContext(ctx, "PasskeyRepository.UpdateSignCount: Failed to update sign count", err)
+		return err
+	}
+
+	return nil
+}
+
+// Delete removes a passkey of the account. It returns false when the account has no such passkey.
+func (r *passkeyRepository) Delete(ctx context.Context, accountID, id uuid.UUID) (bool, error) {
+	ctx, span := configs.StartSpan(ctx, "PasskeyRepository.Delete")
+	defer span.End()
+
+	result, err := r.writer.ExecContext(ctx, `DELETE FROM passkeys WHERE id = $1 AND account_id = $2`, id, accountID)
+	if err != nil {
+		logs.ErrorContext(ctx, "PasskeyRepository.Delete: Failed to delete passkey", err)
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		logs.ErrorContext(ctx, "PasskeyRepository.Delete: Failed to read affected rows", err)
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func (r *passkeyRepository) CreateCeremony(ctx context.Context, ceremony *entities.PasskeyCeremony) error {
+	ctx, span := configs.StartSpan(ctx, "PasskeyRepository.CreateCeremony")
+	defer span.End()
+
+	query := `INSERT INTO passkey_ceremonies (id, account_id, kind, session_data, expires_at, created_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := r.writer.ExecContext(ctx, query, ceremony.ID, ceremony.AccountID, ceremony.Kind, string(ceremony.SessionData), ceremony.ExpiresAt, ceremony.CreatedAt); err != nil {
+		logs.ErrorContext(ctx, "PasskeyRepository.CreateCeremony: Failed to create ceremony", err)
+		return err
+	}
+
+	return nil
+}
+
+// ConsumeCeremony deletes and returns an unexpired ceremony of the kind, so each challenge is answered once.
+// It returns sql.ErrNoRows when the ceremony is unknown, expired or already completed.
+func (r *passkeyRepository) ConsumeCeremony(ctx context.Context, id uuid.UUID, kind entities.CeremonyKind) (*entities.PasskeyCeremony, error) {
+	ctx, span := configs.StartSpan(ctx, "PasskeyRepository.ConsumeCeremony")
+	defer span.End()
+
+	ceremony := new(entities.PasskeyCeremony)
+	query := `DELETE FROM passkey_ceremonies WHERE id = $1 AND kind = $2 AND expires_at > $3 RETURNING *`
+	if err := r.writer.GetContext(ctx, ceremony, query, id, kind, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return ceremony, nil
+}
+
+func (r *passkeyRepository) DeleteExpiredCeremonies(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := configs.StartSpan(ctx, "PasskeyRepository.DeleteExpiredCeremonies")
+	defer span.End()
+
+	result, err := r.writer.ExecContext(ctx, `DELETE FROM passkey_ceremonies WHERE expires_at < $1`, before)
+	if err != nil {
+		logs.ErrorContext(ctx, "PasskeyRepository.DeleteExpiredCeremonies: Failed to delete expired ceremonies", err)
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
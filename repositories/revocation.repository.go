@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"context"
+	"ticket-booking/configs"
+	"ticket-booking/configs/logs"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RevocationRepository interface {
+	Revoke(ctx context.Context, jti uuid.UUID, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti, sessionID uuid.UUID) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type revocationRepository struct {
+	reader configs.Reader
+	writer *configs.Database
+}
+
+func NewRevocationRepository(reader configs.Reader, writer *configs.Database) RevocationRepository {
+	return &revocationRepository{reader: reader, writer: writer}
+}
+
+func (r *revocationRepository) Revoke(ctx context.Context, jti uuid.UUID, expiresAt time.Time) error {
+	ctx, span := configs.StartSpan(ctx, "RevocationRepository.Revoke")
+	defer span.End()
+
+	query := `INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING`
+	if _, err := r.writer.ExecContext(ctx, query, jti, expiresAt); err != nil {
+		logs.ErrorContext(ctx, "RevocationRepository.Revoke: Failed to revoke token", err)
+		return err
+	}
+
+	return nil
+}
+
+// IsRevoked reports whether the token itself or the session it was issued for has been revoked.
+// It reads from the writer so a logout is honoured immediately, regardless of replica lag.
+func (r *revocationRepository) IsRevoked(ctx context.Context, jti, sessionID uuid.UUID) (bool, error) {
+	ctx, span := configs.StartSpan(ctx, "RevocationRepository.IsRevoked")
+	defer span.End()
+
+	var revoked bool
+	query := `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)
+		OR EXISTS (SELECT 1 FROM sessions WHERE id = $2 AND revoked_at IS NOT NULL)`
+	if err := r.writer.GetContext(ctx, &revoked, query, jti, sessionID); err != nil {
+		logs.ErrorContext(ctx, "RevocationRepository.IsRevoked: Failed to check revocation", err)
+		return false, err
+	}
+
+	return revoked, nil
+}
+
+func (r *revocationRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := configs.StartSpan(ctx, "RevocationRepository.DeleteExpired")
+	defer span.End()
+
+	result, err := r.writer.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < $1`, before)
+	if err != nil {
+		logs.ErrorContext(ctx, "RevocationRepository.DeleteExpired: Failed to delete expired revocations", err)
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
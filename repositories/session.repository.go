@@ -0,0 +1,186 @@
+package repositories
+
+import (
+	"context"
+	"ticket-booking/configs"
+	"ticket-booking/configs/logs"
+	"ticket-booking/entities"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *entities.Session) error
+	FindByID(ctx context.Context, id uuid.UUID) (*entities.Session, error)
+	FindActiveByAccountID(ctx context.Context, accountID uuid.UUID) ([]*entities.Session, error)
+	Touch(ctx context.Context, id uuid.UUID) error
+	Revoke(ctx context.Context, id uuid.UUID) error
+	RevokeAllByAccountID(ctx context.Context, accountID, except uuid.UUID) ([]uuid.UUID, error)
+	CreateRefreshToken(ctx context.Context, token *entities.RefreshToken) error
+	FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*entities.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, id uuid.UUID) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type sessionRepository struct {
+	reader configs.Reader
+	writer *configs.Database
+}
+
+func NewSessionRepository(reader configs.Reader, writer *configs.Database) SessionRepository {
+	return &sessionRepository{reader: reader, writer: writer}
+}
+
+func (r *sessionRepository) Create(ctx context.Context, session *entities.Session) error {
+	ctx, span := configs.StartSpan(ctx, "SessionRepository.Create")
+	defer span.End()
+
+	query := `INSERT INTO sessions (id, account_id, device, ip_address, created_at, last_used_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := r.writer.ExecContext(ctx, query, session.ID, session.AccountID, session.Device, session.IPAddress, session.CreatedAt, session.LastUsedAt); err != nil {
+		logs.ErrorContext(ctx, "SessionRepository.Create: Failed to create session", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *sessionRepository) FindByID(ctx context.Context, id uuid.UUID) (*entities.Session, error) {
+	ctx, span := configs.StartSpan(ctx, "SessionRepository.FindByID")
+	defer span.End()
+
+	session := new(entities.Session)
+	query := `SELECT * FROM sessions WHERE id = $1`
+	if err := r.writer.GetContext(ctx, session, query, id); err != nil {
+		logs.ErrorContext(ctx, "SessionRepository.FindByID: Failed to retrieve session by ID", err)
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (r *sessionRepository) FindActiveByAccountID(ctx context.Context, accountID uuid.UUID) ([]*entities.Session, error) {
+	ctx, span := configs.StartSpan(ctx, "SessionRepository.FindActiveByAccountID")
+	defer span.End()
+
+	var sessions []*entities.Session
+	query := `SELECT * FROM sessions WHERE account_id = $1 AND revoked_at IS NULL ORDER BY last_used_at DESC`
+	if err := r.reader.SelectContext(ctx, &sessions, query, accountID); err != nil {
+		logs.ErrorContext(ctx, "SessionRepository.FindActiveByAccountID: Failed to retrieve sessions", err)
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (r *sessionRepository) Touch(ctx context.Context, id uuid.UUID) error {
+	ctx, span := configs.StartSpan(ctx, "SessionRepository.Touch")
+	defer span.End()
+
+	query := `UPDATE sessions SET last_used_at = $1 WHERE id = $2`
+	if _, err := r.writer.ExecContext(ctx, query, time.Now(), id); err != nil {
+		logs.ErrorContext(ctx, "SessionRepository.Touch: Failed to update session", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *sessionRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	ctx, span := configs.StartSpan(ctx, "SessionRepository.Revoke")
+	defer span.End()
+
+	query := `UPDATE sessions SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL`
+	if _, err := r.writer.ExecContext(ctx, query, time.Now(), id); err != nil {
+		logs.ErrorContext(ctx, "SessionRepository.Revoke: Failed to revoke session", err)
+		return err
+	}
+
+	return nil
+}
+
+// RevokeAllByAccountID revokes every active session of the account but the excepted one, and returns their IDs.
+// Pass uuid.Nil to revoke them all.
+func (r *sessionRepository) RevokeAllByAccountID(ctx context.Context, accountID, except uuid.UUID) ([]uuid.UUID, error) {
+	ctx, span := configs.StartSpan(ctx, "SessionRepository.RevokeAllByAccountID")
+	defer span.End()
+
+	var ids []uuid.UUID
+	query := `UPDATE sessions SET revoked_at = $1 WHERE account_id = $2 AND id <> $3 AND revoked_at IS NULL RETURNING id`
+	if err := r.writer.SelectContext(ctx, &ids, query, time.Now(), accountID, except); err != nil {
+		logs.ErrorContext(ctx, "SessionRepository.RevokeAllByAccountID: Failed to revoke sessions", err)
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (r *sessionRepository) CreateRefreshToken(ctx context.Context, token *entities.RefreshToken) error {
+	ctx, span := configs.StartSpan(ctx, "SessionRepository.CreateRefreshToken")
+	defer span.End()
+
+	query := `INSERT INTO refresh_tokens (id, session_id, token_hash, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)`
+	if _, err := r.writer.ExecContext(ctx, query, token.ID, token.SessionID, token.TokenHash, token.ExpiresAt, token.CreatedAt); err != nil {
+		logs.ErrorContext(ctx, "SessionRepository.CreateRefreshToken: Failed to create refresh token", err)
+		return err
+	}
+
+	return nil
+}
+
+// FindRefreshTokenByHash reads from the writer, a token that was just issued may not have reached a replica yet.
+func (r *sessionRepository) FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*entities.RefreshToken, error) {
+	ctx, span := configs.StartSpan(ctx, "SessionRepository.FindRefreshTokenByHash")
+	defer span.End()
+
+	token := new(entities.RefreshToken)
+	query := `SELECT * FROM refresh_tokens WHERE token_hash = $1`
+	if err := r.writer.GetContext(ctx, token, query, tokenHash); err != nil {
+		logs.ErrorContext(ctx, "SessionRepository.FindRefreshTokenByHash: Failed to retrieve refresh token", err)
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken marks the token as used. It returns false when the token had already been rotated,
+// which means it is being reused.
+func (r *sessionRepository) RotateRefreshToken(ctx context.Context, id uuid.UUID) (bool, error) {
+	ctx, span := configs.StartSpan(ctx, "SessionRepository.RotateRefreshToken")
+	defer span.End()
+
+	query := `UPDATE refresh_tokens SET rotated_at = $1 WHERE id = $2 AND rotated_at IS NULL`
+	result, err := r.writer.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		logs.ErrorContext(ctx, "SessionRepository.RotateRefreshToken: Failed to rotate refresh token", err)
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		logs.ErrorContext(ctx, "SessionRepository.RotateRefreshToken: Failed to read affected rows", err)
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// DeleteExpired removes refresh tokens that expired before the given time and sessions left without tokens.
+func (r *sessionRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := configs.StartSpan(ctx, "SessionRepository.DeleteExpired")
+	defer span.End()
+
+	result, err := r.writer.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE expires_at < $1`, before)
+	if err != nil {
+		logs.ErrorContext(ctx, "SessionRepository.DeleteExpired: Failed to delete expired refresh tokens", err)
+		return 0, err
+	}
+
+	query := `DELETE FROM sessions s WHERE s.created_at < $1 AND NOT EXISTS (SELECT 1 FROM refresh_tokens t WHERE t.session_id = s.id)`
+	if _, err := r.writer.ExecContext(ctx, query, before); err != nil {
+		logs.ErrorContext(ctx, "SessionRepository.DeleteExpired: Failed to delete empty sessions", err)
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -3,35 +3,40 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"ticket-booking/configs"
 	"ticket-booking/configs/logs"
 	"ticket-booking/entities"
 
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
 )
 
 type TicketRepository interface {
 	FindAll(ctx context.Context, accountID uuid.UUID) ([]*entities.Ticket, error)
 	FindByID(ctx context.Context, accountID uuid.UUID, id uint64) (*entities.Ticket, error)
+	FindByPublicID(ctx context.Context, accountID uuid.UUID, publicID uuid.UUID) (*entities.Ticket, error)
+	FindByPublicIDUnscoped(ctx context.Context, publicID uuid.UUID) (*entities.Ticket, error)
 	Create(ctx context.Context, ticket *entities.Ticket) error
 	Validate(ctx context.Context, ticket *entities.Ticket) error
 	Delete(ctx context.Context, accountID uuid.UUID, id uint64) error
 }
 
 type ticketRepository struct {
-	reader *sqlx.DB
-	writer *sqlx.DB
+	reader configs.Reader
+	writer *configs.Database
 }
 
-func NewTicketRepository(reader, writer *sqlx.DB) TicketRepository {
+func NewTicketRepository(reader configs.Reader, writer *configs.Database) TicketRepository {
 	return &ticketRepository{reader: reader, writer: writer}
 }
 
 func (t *ticketRepository) FindAll(ctx context.Context, accountID uuid.UUID) ([]*entities.Ticket, error) {
+	ctx, span := configs.StartSpan(ctx, "TicketRepository.FindAll")
+	defer span.End()
+
 	var tickets []*entities.Ticket
 	query := `SELECT * FROM tickets WHERE account_id = $1`
 	if err := t.reader.SelectContext(ctx, &tickets, query, accountID); err != nil {
-		logs.Error("TicketRepository.FindAll: Failed to retrieve tickets", err)
+		logs.ErrorContext(ctx, "TicketRepository.FindAll: Failed to retrieve tickets", err)
 		return nil, err
 	}
 
@@ -39,14 +44,54 @@ func (t *ticketRepository) FindAll(ctx context.Context, accountID uuid.UUID) ([]
 }
 
 func (t *ticketRepository) FindByID(ctx context.Context, accountID uuid.UUID, id uint64) (*entities.Ticket, error) {
+	ctx, span := configs.StartSpan(ctx, "TicketRepository.FindByID")
+	defer span.End()
+
 	ticket := new(entities.Ticket)
 	query := `SELECT * FROM tickets WHERE id = $1 AND account_id = $2`
 	if err := t.reader.GetContext(ctx, ticket, query, id, accountID); err != nil {
 		if err == sql.ErrNoRows {
-			logs.Warn("TicketRepository.FindByID: Ticket not found")
+			logs.WarnContext(ctx, "TicketRepository.FindByID: Ticket not found")
 			return nil, nil
 		}
-		logs.Error("TicketRepository.FindByID: Failed to retrieve ticket by ID", err)
+		logs.ErrorContext(ctx, "TicketRepository.FindByID: Failed to retrieve ticket by ID", err)
+		return nil, err
+	}
+
+	return ticket, nil
+}
+
+func (t *ticketRepository) FindByPublicID(ctx context.Context, accountID uuid.UUID, publicID uuid.UUID) (*entities.Ticket, error) {
+	ctx, span := configs.StartSpan(ctx, "TicketRepository.FindByPublicID")
+	defer span.End()
+
+	ticket := new(entities.Ticket)
+	query := `SELECT * FROM tickets WHERE public_id = $1 AND account_id = $2`
+	if err := t.reader.GetContext(ctx, ticket, query, publicID, accountID); err != nil {
+		if err == sql.ErrNoRows {
+			logs.WarnContext(ctx, "TicketRepository.FindByPublicID: Ticket not found")
+			return nil, err
+		}
+		logs.ErrorContext(ctx, "TicketRepository.FindByPublicID: Failed to retrieve ticket by public ID", err)
+		return nil, err
+	}
+
+	return ticket, nil
+}
+
+// FindByPublicIDUnscoped retrieves a ticket regardless of its owner, for staff checking attendees in.
+func (t *ticketRepository) FindByPublicIDUnscoped(ctx context.Context, publicID uuid.UUID) (*entities.Ticket, error) {
+	ctx, span := configs.StartSpan(ctx, "TicketRepository.FindByPublicIDUnscoped")
+	defer span.End()
+
+	ticket := new(entities.Ticket)
+	query := `SELECT * FROM tickets WHERE public_id = $1`
+	if err := t.reader.GetContext(ctx, ticket, query, publicID); err != nil {
+		if err == sql.ErrNoRows {
+			logs.WarnContext(ctx, "TicketRepository.FindByPublicIDUnscoped: Ticket not found")
+			return nil, err
+		}
+		logs.ErrorContext(ctx, "TicketRepository.FindByPublicIDUnscoped: Failed to retrieve ticket by public ID", err)
 		return nil, err
 	}
 
@@ -54,9 +99,12 @@ func (t *ticketRepository) FindByID(ctx context.Context, accountID uuid.UUID, id
 }
 
 func (t *ticketRepository) Create(ctx context.Context, ticket *entities.Ticket) error {
-	query := `INSERT INTO tickets (event_id, account_id, entered, created_at, updated_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`
-	if err := t.writer.QueryRowContext(ctx, query, ticket.EventID, ticket.AccountID, ticket.Entered, ticket.CreatedAt, ticket.UpdatedAt).Scan(&ticket.ID); err != nil {
-		logs.Error("TicketRepository.Create: Failed to create ticket", err)
+	ctx, span := configs.StartSpan(ctx, "TicketRepository.Create")
+	defer span.End()
+
+	query := `INSERT INTO tickets (public_id, event_id, account_id, entered, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	if err := t.writer.GetContext(ctx, &ticket.ID, query, ticket.PublicID, ticket.EventID, ticket.AccountID, ticket.Entered, ticket.CreatedAt, ticket.UpdatedAt); err != nil {
+		logs.ErrorContext(ctx, "TicketRepository.Create: Failed to create ticket", err)
 		return err
 	}
 
@@ -64,9 +112,12 @@ func (t *ticketRepository) Create(ctx context.Context, ticket *entities.Ticket)
 }
 
 func (t *ticketRepository) Validate(ctx context.Context, ticket *entities.Ticket) error {
+	ctx, span := configs.StartSpan(ctx, "TicketRepository.Validate")
+	defer span.End()
+
 	query := `UPDATE tickets SET entered = $1, updated_at = $2 WHERE id = $3 AND account_id = $4`
 	if _, err := t.writer.ExecContext(ctx, query, ticket.Entered, ticket.UpdatedAt, ticket.ID, ticket.AccountID); err != nil {
-		logs.Error("TicketRepository.Validate: Failed to validate ticket", err)
+		logs.ErrorContext(ctx, "TicketRepository.Validate: Failed to validate ticket", err)
 		return err
 	}
 
@@ -74,9 +125,12 @@ func (t *ticketRepository) Validate(ctx context.Context, ticket *entities.Ticket
 }
 
 func (t *ticketRepository) Delete(ctx context.Context, accountID uuid.UUID, id uint64) error {
+	ctx, span := configs.StartSpan(ctx, "TicketRepository.Delete")
+	defer span.End()
+
 	query := `DELETE FROM tickets WHERE id = $1 AND account_id = $2`
 	if _, err := t.writer.ExecContext(ctx, query, id, accountID); err != nil {
-		logs.Error("TicketRepository.Delete: Failed to delete ticket", err)
+		logs.ErrorContext(ctx, "TicketRepository.Delete: Failed to delete ticket", err)
 		return err
 	}
 
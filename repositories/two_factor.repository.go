@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"context"
+	"ticket-booking/configs"
+	"ticket-booking/configs/logs"
+	"ticket-booking/entities"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TwoFactorRepository interface {
+	SetSecret(ctx context.Context, accountID uuid.UUID, secret string) error
+	Enable(ctx context.Context, accountID uuid.UUID, lastStep int64, codes []*entities.RecoveryCode) error
+	Disable(ctx context.Context, accountID uuid.UUID) error
+	UseStep(ctx context.Context, accountID uuid.UUID, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, accountID uuid.UUID, codeHash string) (bool, error)
+}
+
+type twoFactorRepository struct {
+	reader configs.Reader
+	writer *configs.Database
+}
+
+func NewTwoFactorRepository(reader configs.Reader, writer *configs.Database) TwoFactorRepository {
+	return &twoFactorRepository{reader: reader, writer: writer}
+}
+
+// SetSecret stores a pending secret. It only takes effect once Enable confirms it.
+func (r *twoFactorRepository) SetSecret(ctx context.Context, accountID uuid.UUID, secret string) error {
+	ctx, span := configs.StartSpan(ctx, "TwoFactorRepository.SetSecret")
+	defer span.End()
+
+	query := `UPDATE accounts SET totp_secret = $1, totp_last_step = 0, updated_at = $2 WHERE id = $3 AND totp_enabled = FALSE`
+	if _, err := r.writer.ExecContext(ctx, query, secret, time.Now(), accountID); err != nil {
+		logs.ErrorContext(ctx, "TwoFactorRepository.SetSecret: Failed to store secret", err)
+		return err
+	}
+
+	return nil
+}
+
+// Enable turns two-factor authentication on and replaces the account's recovery codes.
+func (r *twoFactorRepository) Enable(ctx context.Context, accountID uuid.UUID, lastStep int64, codes []*entities.RecoveryCode) error {
+	ctx, span := configs.StartSpan(ctx, "TwoFactorRepository.Enable")
+	defer span.End()
+
+	tx, err := r.writer.BeginTxx(ctx, nil)
+	if err != nil {
+		logs.ErrorContext(ctx, "TwoFactorRepository.Enable: Failed to begin transaction", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE accounts SET totp_enabled = TRUE, totp_last_step = $1, updated_at = $2 WHERE id = $3`
+	if _, err := tx.ExecContext(ctx, query, lastStep, time.Now(), accountID); err != nil {
+		logs.ErrorContext(ctx, "TwoFactorRepository.Enable: Failed to enable two-factor authentication", err)
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE account_id = $1`, accountID); err != nil {
+		logs.ErrorContext(ctx, "TwoFactorRepository.Enable: Failed to delete recovery codes", err)
+		return err
+	}
+
+	query = `INSERT INTO recovery_codes (id, account_id, code_hash, created_at) VALUES ($1, $2, $3, $4)`
+	for _, code := range codes {
+		if _, err := tx.ExecContext(ctx, query, code.ID, code.AccountID, code.CodeHash, code.CreatedAt); err != nil {
+			logs.ErrorContext(ctx, "TwoFactorRepository.Enable: Failed to store recovery code", err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logs.ErrorContext(ctx, "TwoFactorRepository.Enable: Failed to commit transaction", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *twoFactorRepository) Disable(ctx context.Context, accountID uuid.UUID) error {
+	ctx, span := configs.StartSpan(ctx, "TwoFactorRepository.Disable")
+	defer span.End()
+
+	tx, err := r.writer.BeginTxx(ctx, nil)
+	if err != nil {
+		logs.ErrorContext(ctx, "TwoFactorRepository.Disable: Failed to begin transaction", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE accounts SET totp_enabled = FALSE, totp_secret = NULL, totp_last_step = 0, updated_at = $1 WHERE id = $2`
+	if _, err := tx.ExecContext(ctx, query, time.Now(), accountID); err != nil {
+		logs.ErrorContext(ctx, "TwoFactorRepository.Disable: Failed to disable two-factor authentication", err)
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE account_id = $1`, accountID); err != nil {
+		logs.ErrorContext(ctx, "TwoFactorRepository.Disable: Failed to delete recovery codes", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logs.ErrorContext(ctx, "TwoFactorRepository.Disable: Failed to commit transaction", err)
+		return err
+	}
+
+	return nil
+}
+
+// UseStep records the time step of an accepted TOTP code. It returns false when the step,
+// or a later one, was already used, so concurrent requests cannot replay the same code.
+func (r *twoFactorRepository) UseStep(ctx context.Context, accountID uuid.UUID, step int64) (bool, error) {
+	ctx, span := configs.StartSpan(ctx, "TwoFactorRepository.UseStep")
+	defer span.End()
+
+	query := `UPDATE accounts SET totp_last_step = $1 WHERE id = $2 AND totp_last_step < $1`
+	result, err := r.writer.ExecContext(ctx, query, step, accountID)
+	if err != nil {
+		logs.ErrorContext(ctx, "TwoFactorRepository.UseStep: Failed to record time step", err)
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		logs.ErrorContext(ctx, "TwoFactorRepository.UseStep: Failed to read affected rows", err)
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// UseRecoveryCode consumes the recovery code. It returns false when no unused code matches.
+func (r *twoFactorRepository) UseRecoveryCode(ctx context.Context, accountID uuid.UUID, codeHash string) (bool, error) {
+	ctx, span := configs.StartSpan(ctx, "TwoFactorRepository.UseRecoveryCode")
+	defer span.End()
+
+	query := `UPDATE recovery_codes SET used_at = $1 WHERE account_id = $2 AND code_hash = $3 AND used_at IS NULL`
+	result, err := r.writer.ExecContext(ctx, query, time.Now(), accountID, codeHash)
+	if err != nil {
+		logs.ErrorContext(ctx, "TwoFactorRepository.UseRecoveryCode: Failed to use recovery code", err)
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		logs.ErrorContext(ctx, "TwoFactorRepository.UseRecoveryCode: Failed to read affected rows", err)
+		return false, err
+	}
+
+	return rows == 1, nil
+}
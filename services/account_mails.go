@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"ticket-booking/entities"
+)
+
+// AccountMails composes and sends the emails of the account flows.
+type AccountMails interface {
+	SendVerification(ctx context.Context, account *entities.Account, token string) error
+	SendPasswordReset(ctx context.Context, account *entities.Account, token string) error
+	SendMagicLink(ctx context.Context, account *entities.Account, token string) error
+	SendEmailChange(ctx context.Context, account *entities.Account, email, token string) error
+	SendEmailChangeNotice(ctx context.Context, account *entities.Account, email string) error
+}
+
+type accountMails struct {
+	mailer  Mailer
+	baseURL string
+}
+
+// NewAccountMails builds links to the frontend found at baseURL.
+func NewAccountMails(mailer Mailer, baseURL string) *accountMails {
+	return &accountMails{
+		mailer:  mailer,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func (m *accountMails) SendVerification(ctx context.Context, account *entities.Account, token string) error {
+	return m.mailer.Send(ctx, &Mail{
+		To:      account.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nIf you did not create an account, ignore this email.\n",
+			account.Name, m.link("/verify-email", token)),
+	})
+}
+
+func (m *accountMails) SendPasswordReset(ctx context.Context, account *entities.Account, token string) error {
+	return m.mailer.Send(ctx, &Mail{
+		To:      account.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nA password reset was requested for your account. Open the link below to choose a new password:\n\n%s\n\nThe link expires in one hour. If you did not request it, ignore this email.\n",
+			account.Name, m.link("/reset-password", token)),
+	})
+}
+
+func (m *accountMails) SendMagicLink(ctx context.Context, account *entities.Account, token string) error {
+	return m.mailer.Send(ctx, &Mail{
+		To:      account.Email,
+		Subject: "Your sign-in link",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to sign in:\n\n%s\n\nThe link can be used once and expires in 15 minutes. If you did not request it, ignore this email.\n",
+			account.Name, m.link("/magic-link", token)),
+	})
+}
+
+// SendEmailChange sends the confirmation link to the new address, which the account does not use yet.
+func (m *accountMails) SendEmailChange(ctx context.Context, account *entities.Account, email, token string) error {
+	return m.mailer.Send(ctx, &Mail{
+		To:      email,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to use this address for your account:\n\n%s\n\nThe link expires in 24 hours. If you did not request it, ignore this email.\n",
+			account.Name, m.link("/confirm-email-change", token)),
+	})
+}
+
+// SendEmailChangeNotice warns the current address that a change to email was requested.
+func (m *accountMails) SendEmailChangeNotice(ctx context.Context, account *entities.Account, email string) error {
+	return m.mailer.Send(ctx, &Mail{
+		To:      account.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("Hi %s,\n\nA change of your account's email address to %s was requested. It takes effect once confirmed from that address.\n\nIf you did not request it, change your password right away.\n",
+			account.Name, email),
+	})
+}
+
+func (m *accountMails) link(path, token string) string {
+	return m.baseURL + path + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ticket-booking/configs/logs"
+	"ticket-booking/entities"
+	"ticket-booking/repositories"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// AccountTokens issues the signed, single-use and expiring tokens sent by email.
+// The signature makes them tamper-proof, the database row makes them single-use.
+type AccountTokens interface {
+	Issue(ctx context.Context, accountID uuid.UUID, purpose entities.TokenPurpose) (string, error)
+	Consume(ctx context.Context, token string, purpose entities.TokenPurpose) (uuid.UUID, error)
+}
+
+var (
+	ErrAccountTokenInvalid     = errors.New("invalid, expired or already used token")
+	ErrAccountTokenRateLimited = errors.New("too many tokens requested")
+)
+
+// accountTokenExpiry is how long each kind of token stays valid.
+var accountTokenExpiry = map[entities.TokenPurpose]time.Duration{
+	entities.TokenPurposeEmailVerification: 24 * time.Hour,
+	entities.TokenPurposePasswordReset:     time.Hour,
+	entities.TokenPurposeMagicLink:         15 * time.Minute,
+	entities.TokenPurposeEmailChange:       24 * time.Hour,
+}
+
+// accountTokenLimit caps how many tokens of a kind an account can be sent within a window,
+// so the endpoint cannot be used to flood someone's inbox.
+type accountTokenLimit struct {
+	max    int
+	window time.Duration
+}
+
+var accountTokenLimits = map[entities.TokenPurpose]accountTokenLimit{
+	entities.TokenPurposePasswordReset: {max: 3, window: time.Hour},
+	entities.TokenPurposeMagicLink:     {max: 3, window: 15 * time.Minute},
+	entities.TokenPurposeEmailChange:   {max: 3, window: time.Hour},
+}
+
+type accountTokens struct {
+	tokenization Tokenization
+	repository   repositories.AccountTokenRepository
+}
+
+func NewAccountTokens(tokenization Tokenization, repository repositories.AccountTokenRepository) *accountTokens {
+	return &accountTokens{
+		tokenization: tokenization,
+		repository:   repository,
+	}
+}
+
+// Issue creates a token, or returns ErrAccountTokenRateLimited when the purpose's limit is reached.
+func (a *accountTokens) Issue(ctx context.Context, accountID uuid.UUID, purpose entities.TokenPurpose) (string, error) {
+	model := entities.NewAccountToken(accountID, purpose, time.Now().Add(accountTokenExpiry[purpose]))
+
+	if limit, ok := accountTokenLimits[purpose]; ok {
+		created, err := a.repository.CreateWithinLimit(ctx, model, time.Now().Add(-limit.window), limit.max)
+		if err != nil {
+			return "", err
+		}
+		if !created {
+			return "", ErrAccountTokenRateLimited
+		}
+	} else if err := a.repository.Create(ctx, model); err != nil {
+		return "", err
+	}
+
+	return a.tokenization.GenerateActionToken(string(purpose), accountID, model.ID, model.ExpiresAt)
+}
+
+// Consume verifies the token and marks it used, returning the account it was issued for.
+func (a *accountTokens) Consume(ctx context.Context, token string, purpose entities.TokenPurpose) (uuid.UUID, error) {
+	accountID, tokenID, err := a.tokenization.ParseActionToken(token, string(purpose))
+	if err != nil {
+		return uuid.Nil, ErrAccountTokenInvalid
+	}
+
+	used, err := a.repository.Use(ctx, tokenID, purpose)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if !used {
+		return uuid.Nil, ErrAccountTokenInvalid
+	}
+
+	return accountID, nil
+}
+
+// RunCleanup periodically deletes expired account tokens until the context is cancelled.
+func (a *accountTokens) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := a.repository.DeleteExpired(ctx, time.Now())
+			if err != nil {
+				logs.ErrorContext(ctx, "Error deleting expired account tokens", err)
+				continue
+			}
+			logs.DebugContext(ctx, "Deleted expired account tokens", zap.Int64("count", deleted))
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"ticket-booking/configs"
+	"ticket-booking/entities"
+	"ticket-booking/repositories"
+
+	"github.com/google/uuid"
+)
+
+// fakeAccountTokenRepository keeps the tokens in memory, checking the limit and inserting under one lock
+// like the advisory lock of the real repository.
+type fakeAccountTokenRepository struct {
+	repositories.AccountTokenRepository
+	mu     sync.Mutex
+	tokens []*entities.AccountToken
+}
+
+func (r *fakeAccountTokenRepository) Create(_ context.Context, token *entities.AccountToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens = append(r.tokens, token)
+	return nil
+}
+
+func (r *fakeAccountTokenRepository) CreateWithinLimit(_ context.Context, token *entities.AccountToken, since time.Time, max int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	for _, issued := range r.tokens {
+		if issued.AccountID == token.AccountID && issued.Purpose == token.Purpose && issued.CreatedAt.After(since) {
+			count++
+		}
+	}
+	if count >= max {
+		return false, nil
+	}
+
+	r.tokens = append(r.tokens, token)
+	return true, nil
+}
+
+func TestAccountTokensIssueEnforcesLimit(t *testing.T) {
+	tokenization, err := NewTokenization(nil, nil, configs.JWTConfig{
+		Secret:   "test-secret",
+		Issuer:   "ticket-booking",
+		Audience: "ticket-booking",
+		Expiry:   time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewTokenization: %v", err)
+	}
+	repository := &fakeAccountTokenRepository{}
+	accountTokens := NewAccountTokens(tokenization, repository)
+	accountID := uuid.New()
+
+	limit := accountTokenLimits[entities.TokenPurposePasswordReset]
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	issued, limited := 0, 0
+	for range limit.max * 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := accountTokens.Issue(context.Background(), accountID, entities.TokenPurposePasswordReset)
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case err == nil:
+				issued++
+			case errors.Is(err, ErrAccountTokenRateLimited):
+				limited++
+			default:
+				t.Errorf("Issue: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if issued != limit.max || limited != limit.max*2 {
+		t.Errorf("issued %d and limited %d tokens, want %d and %d", issued, limited, limit.max, limit.max*2)
+	}
+
+	// Purposes without a limit are always issued
+	for range limit.max + 1 {
+		if _, err := accountTokens.Issue(context.Background(), accountID, entities.TokenPurposeEmailVerification); err != nil {
+			t.Fatalf("Issue without a limit: %v", err)
+		}
+	}
+}
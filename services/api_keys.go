@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"ticket-booking/configs/logs"
+	"ticket-booking/entities"
+	"ticket-booking/repositories"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix starts every API key, so Auth can tell keys and JWTs apart and leaked keys are easy to scan for.
+const APIKeyPrefix = "tbk_"
+
+// apiKeyDisplayLength is how much of a key is kept in clear to identify it in listings.
+const apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+var ErrAPIKeyInvalid = errors.New("invalid, expired or revoked API key")
+
+// APIKeys manages account-owned API keys. The key itself is returned once, at creation;
+// only its hash is stored.
+type APIKeys interface {
+	Create(ctx context.Context, accountID uuid.UUID, name string, scopes []string, expiresAt *time.Time) (string, *entities.APIKey, error)
+	Authenticate(ctx context.Context, key string) (*entities.Principal, error)
+	FindAll(ctx context.Context, accountID uuid.UUID) ([]*entities.APIKey, error)
+	Revoke(ctx context.Context, accountID, id uuid.UUID) (bool, error)
+}
+
+type apiKeys struct {
+	repository repositories.APIKeyRepository
+}
+
+func NewAPIKeys(repository repositories.APIKeyRepository) *apiKeys {
+	return &apiKeys{repository: repository}
+}
+
+func (a *apiKeys) Create(ctx context.Context, accountID uuid.UUID, name string, scopes []string, expiresAt *time.Time) (string, *entities.APIKey, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		logs.ErrorContext(ctx, "Error generating API key", err)
+		return "", nil, err
+	}
+
+	key := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	model := entities.NewAPIKey(accountID, name, key[:apiKeyDisplayLength], hashToken(key), scopes, expiresAt)
+
+	if err := a.repository.Create(ctx, model); err != nil {
+		return "", nil, err
+	}
+
+	return key, model, nil
+}
+
+// Authenticate resolves a key to a principal holding the key's scopes still granted to its owner.
+func (a *apiKeys) Authenticate(ctx context.Context, key string) (*entities.Principal, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	model, err := a.repository.FindByHash(ctx, hashToken(key))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAPIKeyInvalid
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if model.IsExpired(now) {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	// Tracking the last use is best effort, it must not fail the request
+	_ = a.repository.Touch(ctx, model.ID, now)
+
+	return entities.NewAPIKeyPrincipal(model), nil
+}
+
+func (a *apiKeys) FindAll(ctx context.Context, accountID uuid.UUID) ([]*entities.APIKey, error) {
+	return a.repository.FindByAccountID(ctx, accountID)
+}
+
+func (a *apiKeys) Revoke(ctx context.Context, accountID, id uuid.UUID) (bool, error) {
+	return a.repository.Revoke(ctx, accountID, id)
+}
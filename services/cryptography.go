@@ -3,58 +3,145 @@ package services
 import (
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"strings"
+	"ticket-booking/configs"
 	"ticket-booking/configs/logs"
+
+	"golang.org/x/crypto/argon2"
 )
 
 type Cryptography interface {
 	EncryptPassword(password string) (string, error)
-	VerifyPassword(password, hashedPassword string) (bool, error)
+	// VerifyPassword reports whether the password matches the stored hash and whether
+	// the hash should be replaced because it uses a legacy algorithm or outdated parameters.
+	VerifyPassword(password, hashedPassword string) (match bool, needsRehash bool, err error)
+}
+
+// argon2Params holds the tunable Argon2id cost parameters.
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	saltLength  uint32
+	keyLength   uint32
+}
+
+type cryptography struct {
+	params argon2Params
 }
 
-type cryptography struct{}
+const argon2idPrefix = "$argon2id$"
 
-func NewCryptography() *cryptography {
-	return &cryptography{}
+var errInvalidHashFormat = errors.New("invalid hashed password format")
+
+func NewCryptography(config configs.Argon2Config) *cryptography {
+	params := argon2Params{
+		memory:      config.Memory,
+		iterations:  config.Iterations,
+		parallelism: config.Parallelism,
+		saltLength:  16,
+		keyLength:   32,
+	}
+
+	return &cryptography{params: params}
 }
 
+// EncryptPassword hashes the password with Argon2id and returns it in the PHC string format:
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<hash>
 func (c *cryptography) EncryptPassword(password string) (string, error) {
-	salt, err := generateSalt()
-	if err != nil {
-		logs.Error("Failed to generate salt: %w", err)
+	salt := make([]byte, c.params.saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		logs.Error("Failed to generate salt", err)
 		return "", fmt.Errorf("failed to generate salt: %w", err)
 	}
 
-	hash := generateHash(password, salt)
-	return fmt.Sprintf("%s.%s", salt, hash), nil
+	hash := argon2.IDKey([]byte(password), salt, c.params.iterations, c.params.memory, c.params.parallelism, c.params.keyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		c.params.memory,
+		c.params.iterations,
+		c.params.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	), nil
 }
 
-func (c *cryptography) VerifyPassword(password, hashedPassword string) (bool, error) {
-	parts := splitHashedPassword(hashedPassword)
-	if len(parts) != 2 {
-		return false, fmt.Errorf("invalid hashed password format")
+func (c *cryptography) VerifyPassword(password, hashedPassword string) (bool, bool, error) {
+	if !strings.HasPrefix(hashedPassword, argon2idPrefix) {
+		match, err := verifyLegacyPassword(password, hashedPassword)
+		return match, true, err
 	}
 
-	salt, hash := parts[0], parts[1]
-	return generateHash(password, salt) == hash, nil
+	params, salt, hash, err := decodeArgon2idHash(hashedPassword)
+	if err != nil {
+		return false, false, err
+	}
+
+	computed := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, params.keyLength)
+	if subtle.ConstantTimeCompare(hash, computed) != 1 {
+		return false, false, nil
+	}
+
+	needsRehash := params.memory != c.params.memory ||
+		params.iterations != c.params.iterations ||
+		params.parallelism != c.params.parallelism ||
+		params.keyLength != c.params.keyLength
+
+	return true, needsRehash, nil
 }
 
-func generateSalt() (string, error) {
-	salt := make([]byte, 16)
-	if _, err := rand.Read(salt); err != nil {
-		logs.Error("Failed to generate salt: %w", err)
-		return "", fmt.Errorf("salt generation error: %w", err)
+func decodeArgon2idHash(hashedPassword string) (*argon2Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 {
+		return nil, nil, nil, errInvalidHashFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, nil, nil, errInvalidHashFormat
+	}
+	if version != argon2.Version {
+		return nil, nil, nil, fmt.Errorf("unsupported argon2 version: %d", version)
+	}
+
+	params := new(argon2Params)
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return nil, nil, nil, errInvalidHashFormat
 	}
-	return base64.StdEncoding.EncodeToString(salt), nil
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, errInvalidHashFormat
+	}
+
+	hash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, nil, nil, errInvalidHashFormat
+	}
+
+	params.saltLength = uint32(len(salt))
+	params.keyLength = uint32(len(hash))
+
+	return params, salt, hash, nil
 }
 
-func generateHash(password, salt string) string {
+// verifyLegacyPassword checks hashes produced by the former "salt.sha256(password+salt)" scheme.
+func verifyLegacyPassword(password, hashedPassword string) (bool, error) {
+	parts := strings.SplitN(hashedPassword, ".", 2)
+	if len(parts) != 2 {
+		return false, errInvalidHashFormat
+	}
+
+	salt, hash := parts[0], parts[1]
 	hashBytes := sha256.Sum256([]byte(password + salt))
-	return base64.StdEncoding.EncodeToString(hashBytes[:])
-}
+	computed := base64.StdEncoding.EncodeToString(hashBytes[:])
 
-func splitHashedPassword(hashedPassword string) []string {
-	return strings.SplitN(hashedPassword, ".", 2)
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(hash)) == 1, nil
 }
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ticket-booking/configs"
+	"ticket-booking/dtos/responses"
+	"ticket-booking/migrations"
+)
+
+const (
+	HealthUp   = "up"
+	HealthDown = "down"
+)
+
+// Health checks whether the application can serve traffic.
+type Health interface {
+	Ready(ctx context.Context) *responses.HealthResponse
+}
+
+type health struct {
+	writer   *configs.Database
+	reader   *configs.Replicas
+	migrator *migrations.Migrator
+	server   *configs.Server
+}
+
+func NewHealth(writer *configs.Database, reader *configs.Replicas, migrator *migrations.Migrator, server *configs.Server) *health {
+	return &health{writer: writer, reader: reader, migrator: migrator, server: server}
+}
+
+// Ready runs every check concurrently. The application is ready only when all of them pass; it stops
+// being ready as soon as graceful shutdown starts, so the orchestrator routes traffic elsewhere.
+func (h *health) Ready(ctx context.Context) *responses.HealthResponse {
+	checks := map[string]func(ctx context.Context) error{
+		"server":     h.checkServer,
+		"migrations": h.checkMigrations,
+		"workers":    h.checkWorkers,
+	}
+	// Pings bypass the circuit breakers, so readiness reflects the database rather than the breaker state
+	checks[h.writer.Name()] = h.writer.PingContext
+	for _, pool := range h.reader.Pools() {
+		checks[pool.Name()] = pool.PingContext
+	}
+
+	response := &responses.HealthResponse{
+		Status:     HealthUp,
+		Components: make(map[string]*responses.ComponentHealth, len(checks)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			start := time.Now()
+			err := check(ctx)
+			component := &responses.ComponentHealth{
+				Status:    HealthUp,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				component.Status = HealthDown
+				component.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			response.Components[name] = component
+			if err != nil {
+				response.Status = HealthDown
+			}
+		}()
+	}
+	wg.Wait()
+
+	return response
+}
+
+func (h *health) checkServer(context.Context) error {
+	if h.server.ShuttingDown() {
+		return errors.New("shutting down")
+	}
+
+	return nil
+}
+
+func (h *health) checkMigrations(ctx context.Context) error {
+	pending, err := h.migrator.Pending(ctx)
+	if err != nil {
+		return err
+	}
+
+	if pending > 0 {
+		return fmt.Errorf("%d pending migrations", pending)
+	}
+
+	return nil
+}
+
+func (h *health) checkWorkers(context.Context) error {
+	var stopped []string
+	for name, running := range h.server.Workers() {
+		if !running {
+			stopped = append(stopped, name)
+		}
+	}
+
+	if len(stopped) > 0 {
+		sort.Strings(stopped)
+		return fmt.Errorf("stopped: %s", strings.Join(stopped, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"ticket-booking/configs/logs"
+
+	"go.uber.org/zap"
+)
+
+// Jobs runs work off the request path. Handlers that must answer in the same time whatever the
+// work finds, e.g. whether an email belongs to an account, enqueue it instead of doing it inline.
+type Jobs interface {
+	// Enqueue schedules the job, reporting false when the queue is full. The job's context keeps the
+	// values of ctx, such as the request ID, but not its cancellation.
+	Enqueue(ctx context.Context, name string, job func(ctx context.Context)) bool
+}
+
+// jobTimeout bounds each job, since the request that enqueued it no longer does.
+const jobTimeout = 30 * time.Second
+
+type queuedJob struct {
+	ctx  context.Context
+	name string
+	run  func(ctx context.Context)
+}
+
+type jobs struct {
+	queue chan queuedJob
+}
+
+// NewJobs creates a queue holding up to size jobs; Run executes them.
+func NewJobs(size int) *jobs {
+	return &jobs{queue: make(chan queuedJob, size)}
+}
+
+func (j *jobs) Enqueue(ctx context.Context, name string, job func(ctx context.Context)) bool {
+	select {
+	case j.queue <- queuedJob{ctx: context.WithoutCancel(ctx), name: name, run: job}:
+		return true
+	default:
+		logs.WarnContext(ctx, "Job queue full, dropping job", zap.String("job", name))
+		return false
+	}
+}
+
+// Run executes the queued jobs one at a time until ctx is cancelled, then runs the jobs still queued
+// so the emails already promised are sent.
+func (j *jobs) Run(ctx context.Context) {
+	for {
+		select {
+		case job := <-j.queue:
+			j.run(job)
+		case <-ctx.Done():
+			for {
+				select {
+				case job := <-j.queue:
+					j.run(job)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (j *jobs) run(job queuedJob) {
+	ctx, cancel := context.WithTimeout(job.ctx, jobTimeout)
+	defer cancel()
+
+	logs.DebugContext(ctx, "Running job", zap.String("job", job.name))
+	job.run(ctx)
+}
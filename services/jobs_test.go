@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"ticket-booking/configs/logs"
+)
+
+func TestJobsRunQueuedJobsOnShutdown(t *testing.T) {
+	jobs := NewJobs(2)
+
+	requestCtx, cancelRequest := context.WithCancel(logs.WithRequestID(context.Background(), "request-1"))
+	var ran []string
+	for _, name := range []string{"first", "second"} {
+		if !jobs.Enqueue(requestCtx, name, func(ctx context.Context) {
+			if err := ctx.Err(); err != nil {
+				t.Errorf("job %s ran with a done context: %v", name, err)
+			}
+			if requestID := logs.RequestID(ctx); requestID != "request-1" {
+				t.Errorf("job %s request ID = %q, want request-1", name, requestID)
+			}
+			ran = append(ran, name)
+		}) {
+			t.Fatalf("Enqueue(%s) = false", name)
+		}
+	}
+	// The request finishing does not cancel its jobs
+	cancelRequest()
+
+	if jobs.Enqueue(context.Background(), "third", func(context.Context) {}) {
+		t.Error("Enqueue on a full queue = true")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	jobs.Run(ctx)
+
+	if len(ran) != 2 || ran[0] != "first" || ran[1] != "second" {
+		t.Errorf("ran %q, want the queued jobs in order", ran)
+	}
+}
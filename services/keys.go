@@ -0,0 +1,236 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"ticket-booking/configs/logs"
+	"ticket-booking/dtos/responses"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+// signingKey is a key identified by its kid. Retired keys only hold the public part
+// and are kept so tokens signed before a rotation remain valid until they expire.
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// keySet holds the key used to sign new tokens and every key accepted for verification.
+type keySet struct {
+	active *signingKey
+	keys   map[string]*signingKey
+	// secret is the legacy HS256 secret, only used when no asymmetric keys are configured.
+	secret []byte
+}
+
+// loadKeySet loads the PEM files of dir, named <kid>.pem. Files holding a private key can sign,
+// files holding only a public key are accepted for verification. The active key is activeKid,
+// or the last private key by file name when it is empty.
+func loadKeySet(dir, activeKid, secret string) (*keySet, error) {
+	set := &keySet{keys: make(map[string]*signingKey)}
+
+	if dir == "" {
+		if secret != "" {
+			logs.Warn("JWT_KEYS_DIR not set, signing tokens with the HS256 JWT_SECRET")
+			set.secret = []byte(secret)
+			return set, nil
+		}
+
+		logs.Warn("Neither JWT_KEYS_DIR nor JWT_SECRET set, using an ephemeral key; tokens will not survive a restart")
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+		}
+		key := &signingKey{kid: "ephemeral", method: jwt.SigningMethodEdDSA, private: private, public: private.Public()}
+		set.keys[key.kid] = key
+		set.active = key
+		return set, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var lastPrivate *signingKey
+	for _, file := range files {
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+
+		key, err := loadSigningKey(kid, file)
+		if err != nil {
+			return nil, err
+		}
+
+		set.keys[kid] = key
+		if key.private != nil {
+			lastPrivate = key
+		}
+		logs.Info("Loaded JWT key", zap.String("kid", kid), zap.String("alg", key.method.Alg()), zap.Bool("signing", key.private != nil))
+	}
+
+	if activeKid == "" {
+		set.active = lastPrivate
+	} else if key, ok := set.keys[activeKid]; ok {
+		set.active = key
+	}
+
+	if set.active == nil || set.active.private == nil {
+		return nil, fmt.Errorf("no private key available to sign tokens in %s", dir)
+	}
+
+	return set, nil
+}
+
+func loadSigningKey(kid, file string) (*signingKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %s: %w", kid, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s is not PEM encoded", kid)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("key %s has unsupported PEM type %q", kid, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key %s: %w", kid, err)
+	}
+
+	key := &signingKey{kid: kid}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.private = signer
+		key.public = signer.Public()
+	} else {
+		key.public = parsed
+	}
+
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	case *ecdsa.PublicKey:
+		if public.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("key %s uses an unsupported curve", kid)
+		}
+		key.method = jwt.SigningMethodES256
+	default:
+		return nil, fmt.Errorf("key %s has an unsupported algorithm", kid)
+	}
+
+	return key, nil
+}
+
+// sign signs the claims with the active key, setting its kid in the header.
+func (s *keySet) sign(claims jwt.Claims) (string, error) {
+	if s.active == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	}
+
+	token := jwt.NewWithClaims(s.active.method, claims)
+	token.Header["kid"] = s.active.kid
+
+	return token.SignedString(s.active.private)
+}
+
+// verificationKey is the jwt.Keyfunc resolving the key a token was signed with.
+func (s *keySet) verificationKey(token *jwt.Token) (interface{}, error) {
+	if s.secret != nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id: %q", kid)
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.public, nil
+}
+
+// jwks returns the public keys in JSON Web Key Set format.
+func (s *keySet) jwks() *responses.JWKSResponse {
+	kids := make([]string, 0, len(s.keys))
+	for kid := range s.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	set := &responses.JWKSResponse{Keys: make([]responses.JWK, 0, len(kids))}
+	for _, kid := range kids {
+		jwk, err := toJWK(s.keys[kid])
+		if err != nil {
+			logs.Error("Failed to encode JWK", err, zap.String("kid", kid))
+			continue
+		}
+		set.Keys = append(set.Keys, *jwk)
+	}
+
+	return set
+}
+
+func toJWK(key *signingKey) (*responses.JWK, error) {
+	encode := base64.RawURLEncoding.EncodeToString
+	jwk := &responses.JWK{Kid: key.kid, Use: "sig", Alg: key.method.Alg()}
+
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encode(public.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encode(public)
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = "P-256"
+		jwk.X = encode(public.X.FillBytes(make([]byte, size)))
+		jwk.Y = encode(public.Y.FillBytes(make([]byte, size)))
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+
+	return jwk, nil
+}
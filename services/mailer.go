@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"ticket-booking/configs"
+	"ticket-booking/configs/logs"
+
+	"go.uber.org/zap"
+)
+
+// Mail is a plain-text email message.
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails. The SMTP implementation is used in production,
+// the outbox implementation writes messages to disk for local development and tests.
+type Mailer interface {
+	Send(ctx context.Context, mail *Mail) error
+}
+
+type smtpMailer struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+type outboxMailer struct {
+	dir  string
+	from string
+}
+
+// NewMailer returns the mailer selected by the driver, "smtp" or "outbox".
+func NewMailer(config configs.MailerConfig) Mailer {
+	if strings.ToLower(config.Driver) == "smtp" {
+		return &smtpMailer{
+			host:     config.SMTP.Host,
+			port:     strconv.Itoa(config.SMTP.Port),
+			username: config.SMTP.Username,
+			password: config.SMTP.Password,
+			from:     config.From,
+		}
+	}
+
+	logs.Warn("Mailer driver not set to smtp, writing emails to the outbox directory", zap.String("dir", config.OutboxDir))
+
+	return &outboxMailer{dir: config.OutboxDir, from: config.From}
+}
+
+func (m *smtpMailer) Send(ctx context.Context, mail *Mail) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(net.JoinHostPort(m.host, m.port), auth, m.from, []string{mail.To}, formatMail(m.from, mail))
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-done:
+		if err != nil {
+			logs.ErrorContext(ctx, "Failed to send email", err, zap.String("subject", mail.Subject))
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+		return nil
+	}
+}
+
+func (m *outboxMailer) Send(_ context.Context, mail *Mail) error {
+	if err := os.MkdirAll(m.dir, os.ModePerm); err != nil {
+		logs.Error("Failed to create outbox directory", err)
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+	if err := os.WriteFile(filepath.Join(m.dir, name), formatMail(m.from, mail), 0o600); err != nil {
+		logs.Error("Failed to write email to outbox", err)
+		return err
+	}
+
+	logs.Info("Email written to outbox", zap.String("file", name), zap.String("subject", mail.Subject))
+	return nil
+}
+
+// formatMail renders the message in RFC 5322 format.
+func formatMail(from string, mail *Mail) []byte {
+	var builder strings.Builder
+	builder.WriteString("From: " + from + "\r\n")
+	builder.WriteString("To: " + mail.To + "\r\n")
+	builder.WriteString("Subject: " + mail.Subject + "\r\n")
+	builder.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(mail.Body, "\n", "\r\n"))
+
+	return []byte(builder.String())
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"ticket-booking/configs"
+	"ticket-booking/configs/logs"
+	"ticket-booking/entities"
+	"ticket-booking/repositories"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+// OIDC is the relying-party side of "Sign in with ..." through OpenID Connect providers:
+// the authorization code flow with PKCE, provider discovery and ID token validation.
+type OIDC interface {
+	AuthorizationURL(ctx context.Context, provider string) (url, state string, err error)
+	Exchange(ctx context.Context, provider, code, state string) (*OIDCIdentity, error)
+}
+
+// OIDCIdentity is the user asserted by a validated ID token.
+type OIDCIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+var (
+	ErrOIDCProviderUnknown = errors.New("unknown identity provider")
+	ErrOIDCStateInvalid    = errors.New("invalid, expired or already used authorization state")
+	ErrOIDCTokenInvalid    = errors.New("invalid ID token")
+)
+
+// OIDCStateExpiry bounds how long the user may take to sign in at the provider.
+const OIDCStateExpiry = 10 * time.Minute
+
+type oidcProvider struct {
+	name         string
+	issuer       string
+	clientID     string
+	clientSecret string
+	scopes       []string
+	mu           sync.Mutex
+	provider     *oidc.Provider
+	verifier     *oidc.IDTokenVerifier
+}
+
+type openIDConnect struct {
+	repository  repositories.OIDCRepository
+	httpClient  *http.Client
+	redirectURL string
+	providers   map[string]*oidcProvider
+}
+
+// NewOIDC configures the relying party for every provider in the configuration.
+// All calls to the providers go through httpClient.
+func NewOIDC(repository repositories.OIDCRepository, httpClient *http.Client, config configs.OIDCConfig) *openIDConnect {
+	providers := make(map[string]*oidcProvider)
+	for _, settings := range config.Providers {
+		name := strings.ToLower(settings.Name)
+		provider := &oidcProvider{
+			name:         name,
+			issuer:       settings.Issuer,
+			clientID:     settings.ClientID,
+			clientSecret: settings.ClientSecret,
+			scopes:       settings.Scopes,
+		}
+		if len(provider.scopes) == 0 {
+			provider.scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+		}
+
+		providers[name] = provider
+	}
+
+	return &openIDConnect{
+		repository:  repository,
+		httpClient:  httpClient,
+		redirectURL: config.RedirectURL,
+		providers:   providers,
+	}
+}
+
+// AuthorizationURL starts a sign-in at the provider. The state, nonce and PKCE verifier are stored
+// until the provider redirects back to the callback. The state is returned so the caller can bind it
+// to the browser that started the sign-in.
+func (o *openIDConnect) AuthorizationURL(ctx context.Context, name string) (string, string, error) {
+	provider, config, err := o.discover(ctx, name)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+
+	nonce, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+
+	verifier := oauth2.GenerateVerifier()
+
+	model := entities.NewOIDCState(state, provider.name, nonce, verifier, time.Now().Add(OIDCStateExpiry))
+	if err := o.repository.CreateState(ctx, model); err != nil {
+		return "", "", err
+	}
+
+	return config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), state, nil
+}
+
+// Exchange completes a sign-in: it consumes the state, redeems the code with the PKCE verifier
+// and validates the returned ID token, including its nonce.
+func (o *openIDConnect) Exchange(ctx context.Context, name, code, state string) (*OIDCIdentity, error) {
+	provider, config, err := o.discover(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	model, err := o.repository.ConsumeState(ctx, state, provider.name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOIDCStateInvalid
+		}
+		return nil, err
+	}
+
+	token, err := config.Exchange(o.clientContext(ctx), code, oauth2.VerifierOption(model.CodeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("code exchange failed: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, ErrOIDCTokenInvalid
+	}
+
+	idToken, err := provider.verifier.Verify(o.clientContext(ctx), rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCTokenInvalid, err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(model.Nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrOIDCTokenInvalid)
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCTokenInvalid, err)
+	}
+
+	return &OIDCIdentity{
+		Provider:      provider.name,
+		Subject:       idToken.Subject,
+		Email:         entities.NormalizeEmail(claims.Email),
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	}, nil
+}
+
+// discover fetches the provider's metadata on first use, so an unreachable provider does not
+// prevent the application from starting, and returns its OAuth2 configuration.
+func (o *openIDConnect) discover(ctx context.Context, name string) (*oidcProvider, *oauth2.Config, error) {
+	provider, ok := o.providers[strings.ToLower(name)]
+	if !ok {
+		return nil, nil, ErrOIDCProviderUnknown
+	}
+
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if provider.provider == nil {
+		discovered, err := oidc.NewProvider(o.clientContext(ctx), provider.issuer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("discovery of %s failed: %w", provider.name, err)
+		}
+
+		provider.provider = discovered
+		provider.verifier = discovered.Verifier(&oidc.Config{ClientID: provider.clientID})
+	}
+
+	return provider, &oauth2.Config{
+		ClientID:     provider.clientID,
+		ClientSecret: provider.clientSecret,
+		Endpoint:     provider.provider.Endpoint(),
+		RedirectURL:  strings.ReplaceAll(o.redirectURL, "{provider}", provider.name),
+		Scopes:       provider.scopes,
+	}, nil
+}
+
+// clientContext routes the calls made by the oidc and oauth2 packages through the configured client.
+func (o *openIDConnect) clientContext(ctx context.Context) context.Context {
+	ctx = oidc.ClientContext(ctx, o.httpClient)
+	return context.WithValue(ctx, oauth2.HTTPClient, o.httpClient)
+}
+
+// RunCleanup periodically removes authorization states that were never completed.
+func (o *openIDConnect) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := o.repository.DeleteExpiredStates(ctx, time.Now())
+			if err != nil {
+				logs.ErrorContext(ctx, "Error deleting expired OIDC states", err)
+			} else {
+				logs.DebugContext(ctx, "Deleted expired OIDC states", zap.Int64("count", deleted))
+			}
+		}
+	}
+}
+
+// randomString returns 32 random bytes, base64url encoded.
+func randomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,362 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"ticket-booking/configs"
+	"ticket-booking/dtos/responses"
+	"ticket-booking/entities"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+)
+
+const (
+	mockClientID     = "ticket-booking"
+	mockClientSecret = "secret"
+)
+
+// mockIdentityProvider is an OpenID Connect provider serving discovery, JWKS and token endpoints.
+// Codes are issued by authorize instead of an interactive sign-in.
+type mockIdentityProvider struct {
+	server *httptest.Server
+	key    *ecdsa.PrivateKey
+	mu     sync.Mutex
+	grants map[string]mockGrant
+}
+
+// mockGrant is what the provider remembers about an authorization code.
+type mockGrant struct {
+	challenge   string
+	redirectURI string
+	claims      jwt.MapClaims
+}
+
+func newMockIdentityProvider(t *testing.T) *mockIdentityProvider {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &mockIdentityProvider{key: key, grants: make(map[string]mockGrant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", provider.discovery)
+	mux.HandleFunc("GET /jwks", provider.jwks)
+	mux.HandleFunc("POST /token", provider.token)
+	provider.server = httptest.NewServer(mux)
+	t.Cleanup(provider.server.Close)
+
+	return provider
+}
+
+func (p *mockIdentityProvider) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.server.URL,
+		"authorization_endpoint":                p.server.URL + "/authorize",
+		"token_endpoint":                        p.server.URL + "/token",
+		"jwks_uri":                              p.server.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"ES256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *mockIdentityProvider) jwks(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, responses.JWKSResponse{Keys: []responses.JWK{{
+		Kty: "EC",
+		Kid: "mock",
+		Use: "sig",
+		Alg: "ES256",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(p.key.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(p.key.Y.FillBytes(make([]byte, 32))),
+	}}})
+}
+
+// token redeems a code once, checking the client, the redirect URI and the PKCE verifier.
+func (p *mockIdentityProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != mockClientID || clientSecret != mockClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	p.mu.Lock()
+	grant, ok := p.grants[r.PostForm.Get("code")]
+	delete(p.grants, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("redirect_uri") != grant.redirectURI ||
+		oauth2.S256ChallengeFromVerifier(r.PostForm.Get("code_verifier")) != grant.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodES256, grant.claims)
+	idToken.Header["kid"] = "mock"
+	signed, err := idToken.SignedString(p.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": uuid.NewString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+	})
+}
+
+// authorize stands in for the user signing in at the provider: it checks the authorization URL
+// and returns the code the provider would redirect back with. The claims override the defaults.
+func (p *mockIdentityProvider) authorize(t *testing.T, authorizationURL string, claims jwt.MapClaims) (code, state string) {
+	t.Helper()
+
+	parsed, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := parsed.Query()
+	if query.Get("client_id") != mockClientID || query.Get("response_type") != "code" {
+		t.Fatalf("unexpected authorization request %s", authorizationURL)
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("authorization request without a PKCE challenge: %s", authorizationURL)
+	}
+	if query.Get("state") == "" || query.Get("nonce") == "" {
+		t.Fatalf("authorization request without state or nonce: %s", authorizationURL)
+	}
+
+	now := time.Now()
+	grant := mockGrant{
+		challenge:   query.Get("code_challenge"),
+		redirectURI: query.Get("redirect_uri"),
+		claims: jwt.MapClaims{
+			"iss":            p.server.URL,
+			"aud":            mockClientID,
+			"sub":            "subject-1",
+			"iat":            now.Unix(),
+			"exp":            now.Add(time.Hour).Unix(),
+			"nonce":          query.Get("nonce"),
+			"email":          "Jane.Doe@Example.com",
+			"email_verified": true,
+			"name":           "Jane Doe",
+		},
+	}
+	for name, value := range claims {
+		grant.claims[name] = value
+	}
+
+	code = uuid.NewString()
+	p.mu.Lock()
+	p.grants[code] = grant
+	p.mu.Unlock()
+
+	return code, query.Get("state")
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// fakeOIDCRepository keeps the states in memory, with the semantics of the SQL repository.
+type fakeOIDCRepository struct {
+	mu     sync.Mutex
+	states map[string]*entities.OIDCState
+}
+
+func newFakeOIDCRepository() *fakeOIDCRepository {
+	return &fakeOIDCRepository{states: make(map[string]*entities.OIDCState)}
+}
+
+func (r *fakeOIDCRepository) CreateState(_ context.Context, state *entities.OIDCState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.states[state.State] = state
+	return nil
+}
+
+func (r *fakeOIDCRepository) ConsumeState(_ context.Context, state, provider string) (*entities.OIDCState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	model, ok := r.states[state]
+	if !ok || model.Provider != provider || !model.ExpiresAt.After(time.Now()) {
+		return nil, sql.ErrNoRows
+	}
+	delete(r.states, state)
+
+	return model, nil
+}
+
+func (r *fakeOIDCRepository) DeleteExpiredStates(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakeOIDCRepository) FindIdentity(context.Context, string, string) (*entities.AccountIdentity, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeOIDCRepository) CreateIdentity(context.Context, *entities.AccountIdentity) error {
+	return nil
+}
+
+func (r *fakeOIDCRepository) state(t *testing.T, state string) *entities.OIDCState {
+	t.Helper()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	model, ok := r.states[state]
+	if !ok {
+		t.Fatalf("state %q was not stored", state)
+	}
+	return model
+}
+
+func newTestOIDC(t *testing.T) (*openIDConnect, *mockIdentityProvider, *fakeOIDCRepository) {
+	t.Helper()
+
+	provider := newMockIdentityProvider(t)
+	repository := newFakeOIDCRepository()
+	oidc := NewOIDC(repository, provider.server.Client(), configs.OIDCConfig{
+		RedirectURL: "http://localhost:3000/api/auth/oidc/{provider}/callback",
+		Providers: []configs.OIDCProviderConfig{{
+			Name:         "Mock",
+			Issuer:       provider.server.URL,
+			ClientID:     mockClientID,
+			ClientSecret: mockClientSecret,
+		}},
+	})
+
+	return oidc, provider, repository
+}
+
+// signIn starts a sign-in and has the provider authorize it with the given claims.
+func signIn(t *testing.T, oidc *openIDConnect, provider *mockIdentityProvider, claims jwt.MapClaims) (code, state string) {
+	t.Helper()
+
+	authorizationURL, _, err := oidc.AuthorizationURL(context.Background(), "mock")
+	if err != nil {
+		t.Fatalf("AuthorizationURL: %v", err)
+	}
+
+	return provider.authorize(t, authorizationURL, claims)
+}
+
+func TestOIDCExchange(t *testing.T) {
+	oidc, provider, _ := newTestOIDC(t)
+	code, state := signIn(t, oidc, provider, nil)
+
+	identity, err := oidc.Exchange(context.Background(), "mock", code, state)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	want := OIDCIdentity{Provider: "mock", Subject: "subject-1", Email: "jane.doe@example.com", EmailVerified: true, Name: "Jane Doe"}
+	if *identity != want {
+		t.Errorf("identity = %+v, want %+v", *identity, want)
+	}
+}
+
+func TestOIDCExchangeSendsPKCEVerifier(t *testing.T) {
+	oidc, provider, repository := newTestOIDC(t)
+	code, state := signIn(t, oidc, provider, nil)
+
+	// A verifier that does not match the challenge sent to the provider must fail the exchange
+	repository.state(t, state).CodeVerifier = oauth2.GenerateVerifier()
+
+	identity, err := oidc.Exchange(context.Background(), "mock", code, state)
+	if err == nil {
+		t.Fatalf("Exchange with a wrong verifier returned %+v", identity)
+	}
+	if errors.Is(err, ErrOIDCStateInvalid) || errors.Is(err, ErrOIDCTokenInvalid) {
+		t.Errorf("Exchange error = %v, want a code exchange failure", err)
+	}
+}
+
+func TestOIDCExchangeRejectsNonceMismatch(t *testing.T) {
+	oidc, provider, _ := newTestOIDC(t)
+	code, state := signIn(t, oidc, provider, jwt.MapClaims{"nonce": "replayed-nonce"})
+
+	if _, err := oidc.Exchange(context.Background(), "mock", code, state); !errors.Is(err, ErrOIDCTokenInvalid) {
+		t.Errorf("Exchange error = %v, want %v", err, ErrOIDCTokenInvalid)
+	}
+}
+
+func TestOIDCExchangeRejectsTokenForAnotherClient(t *testing.T) {
+	oidc, provider, _ := newTestOIDC(t)
+	code, state := signIn(t, oidc, provider, jwt.MapClaims{"aud": "another-client"})
+
+	if _, err := oidc.Exchange(context.Background(), "mock", code, state); !errors.Is(err, ErrOIDCTokenInvalid) {
+		t.Errorf("Exchange error = %v, want %v", err, ErrOIDCTokenInvalid)
+	}
+}
+
+func TestOIDCExchangeRejectsReusedState(t *testing.T) {
+	oidc, provider, _ := newTestOIDC(t)
+	code, state := signIn(t, oidc, provider, nil)
+
+	if _, err := oidc.Exchange(context.Background(), "mock", code, state); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	if _, err := oidc.Exchange(context.Background(), "mock", code, state); !errors.Is(err, ErrOIDCStateInvalid) {
+		t.Errorf("second Exchange error = %v, want %v", err, ErrOIDCStateInvalid)
+	}
+}
+
+func TestOIDCExchangeRejectsExpiredState(t *testing.T) {
+	oidc, provider, repository := newTestOIDC(t)
+	code, state := signIn(t, oidc, provider, nil)
+
+	repository.state(t, state).ExpiresAt = time.Now().Add(-time.Second)
+
+	if _, err := oidc.Exchange(context.Background(), "mock", code, state); !errors.Is(err, ErrOIDCStateInvalid) {
+		t.Errorf("Exchange error = %v, want %v", err, ErrOIDCStateInvalid)
+	}
+}
+
+func TestOIDCExchangeReportsUnverifiedEmail(t *testing.T) {
+	oidc, provider, _ := newTestOIDC(t)
+	code, state := signIn(t, oidc, provider, jwt.MapClaims{"email_verified": false})
+
+	identity, err := oidc.Exchange(context.Background(), "mock", code, state)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if identity.EmailVerified {
+		t.Error("EmailVerified = true for an email the provider did not verify")
+	}
+}
@@ -0,0 +1,330 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ticket-booking/configs"
+	"ticket-booking/configs/logs"
+	"ticket-booking/entities"
+	"ticket-booking/repositories"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Passkeys runs the WebAuthn registration and login ceremonies. Logins are discoverable: the browser
+// offers the passkeys it holds for the site, so no email is asked first.
+type Passkeys interface {
+	BeginRegistration(ctx context.Context, account *entities.Account) (uuid.UUID, *protocol.CredentialCreation, error)
+	FinishRegistration(ctx context.Context, account *entities.Account, ceremonyID uuid.UUID, name string, response []byte) (*entities.Passkey, error)
+	BeginLogin(ctx context.Context) (uuid.UUID, *protocol.CredentialAssertion, error)
+	FinishLogin(ctx context.Context, ceremonyID uuid.UUID, response []byte) (uuid.UUID, error)
+	FindAll(ctx context.Context, accountID uuid.UUID) ([]*entities.Passkey, error)
+	Delete(ctx context.Context, accountID, id uuid.UUID) (bool, error)
+}
+
+var (
+	ErrPasskeyCeremonyInvalid = errors.New("invalid, expired or already completed passkey ceremony")
+	ErrPasskeyInvalid         = errors.New("passkey verification failed")
+	ErrPasskeyCloned          = errors.New("passkey sign count went backwards, the credential may be cloned")
+)
+
+// passkeyCeremonyExpiry bounds how long the user may take to answer the authenticator prompt.
+const passkeyCeremonyExpiry = 5 * time.Minute
+
+type passkeys struct {
+	webauthn   *webauthn.WebAuthn
+	repository repositories.PasskeyRepository
+}
+
+// passkeyUser adapts an account and its passkeys to the webauthn.User interface.
+// The user handle stored in the authenticator is the account ID.
+type passkeyUser struct {
+	id          uuid.UUID
+	name        string
+	displayName string
+	passkeys    []*entities.Passkey
+}
+
+func (u *passkeyUser) WebAuthnID() []byte {
+	return u.id[:]
+}
+
+func (u *passkeyUser) WebAuthnName() string {
+	return u.name
+}
+
+func (u *passkeyUser) WebAuthnDisplayName() string {
+	return u.displayName
+}
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.passkeys))
+	for _, passkey := range u.passkeys {
+		transports := make([]protocol.AuthenticatorTransport, 0, len(passkey.Transports))
+		for _, transport := range passkey.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+
+		credentials = append(credentials, webauthn.Credential{
+			ID:              passkey.CredentialID,
+			PublicKey:       passkey.PublicKey,
+			AttestationType: passkey.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: passkey.BackupEligible,
+				BackupState:    passkey.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    passkey.AAGUID,
+				SignCount: uint32(passkey.SignCount),
+			},
+		})
+	}
+
+	return credentials
+}
+
+// NewPasskeys configures the relying party. Without explicit origins, only baseURL is accepted.
+func NewPasskeys(repository repositories.PasskeyRepository, config configs.WebAuthnConfig, baseURL string) (*passkeys, error) {
+	origins := config.RPOrigins
+	if len(origins) == 0 {
+		origins = []string{baseURL}
+	}
+
+	var rpOrigins []string
+	for _, origin := range origins {
+		rpOrigins = append(rpOrigins, strings.TrimSuffix(origin, "/"))
+	}
+
+	relyingParty, err := webauthn.New(&webauthn.Config{
+		RPID:          config.RPID,
+		RPDisplayName: config.RPName,
+		RPOrigins:     rpOrigins,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementRequired,
+			UserVerification: protocol.VerificationRequired,
+		},
+	})
+	if err != nil {
+		logs.Error("Error configuring WebAuthn", err)
+		return nil, err
+	}
+
+	return &passkeys{
+		webauthn:   relyingParty,
+		repository: repository,
+	}, nil
+}
+
+func (p *passkeys) BeginRegistration(ctx context.Context, account *entities.Account) (uuid.UUID, *protocol.CredentialCreation, error) {
+	user, err := p.loadUser(ctx, account)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(user.passkeys))
+	for _, credential := range user.WebAuthnCredentials() {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	options, session, err := p.webauthn.BeginRegistration(user, webauthn.WithExclusions(exclusions))
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	ceremonyID, err := p.saveCeremony(ctx, uuid.NullUUID{UUID: account.ID, Valid: true}, entities.CeremonyRegistration, session)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	return ceremonyID, options, nil
+}
+
+func (p *passkeys) FinishRegistration(ctx context.Context, account *entities.Account, ceremonyID uuid.UUID, name string, response []byte) (*entities.Passkey, error) {
+	ceremony, session, err := p.consumeCeremony(ctx, ceremonyID, entities.CeremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+
+	if ceremony.AccountID.UUID != account.ID {
+		return nil, ErrPasskeyCeremonyInvalid
+	}
+
+	user, err := p.loadUser(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPasskeyInvalid, err)
+	}
+
+	credential, err := p.webauthn.CreateCredential(user, *session, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPasskeyInvalid, err)
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	passkey := entities.NewPasskey(account.ID, name, credential.ID, credential.PublicKey, credential.AttestationType, credential.Authenticator.AAGUID,
+		credential.Authenticator.SignCount, transports, credential.Flags.BackupEligible, credential.Flags.BackupState)
+	if err := p.repository.Create(ctx, passkey); err != nil {
+		return nil, err
+	}
+
+	return passkey, nil
+}
+
+func (p *passkeys) BeginLogin(ctx context.Context) (uuid.UUID, *protocol.CredentialAssertion, error) {
+	options, session, err := p.webauthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	ceremonyID, err := p.saveCeremony(ctx, uuid.NullUUID{}, entities.CeremonyLogin, session)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	return ceremonyID, options, nil
+}
+
+// FinishLogin verifies the assertion and returns the account it signs in. The stored sign count is
+// updated; an assertion whose count does not increase is refused as a possible clone.
+func (p *passkeys) FinishLogin(ctx context.Context, ceremonyID uuid.UUID, response []byte) (uuid.UUID, error) {
+	_, session, err := p.consumeCeremony(ctx, ceremonyID, entities.CeremonyLogin)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: %v", ErrPasskeyInvalid, err)
+	}
+
+	var user *passkeyUser
+	credential, err := p.webauthn.ValidateDiscoverableLogin(func(_, userHandle []byte) (webauthn.User, error) {
+		accountID, err := uuid.FromBytes(userHandle)
+		if err != nil {
+			return nil, err
+		}
+
+		user = &passkeyUser{id: accountID}
+		if user.passkeys, err = p.repository.FindByAccountID(ctx, accountID); err != nil {
+			return nil, err
+		}
+
+		return user, nil
+	}, *session, parsed)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: %v", ErrPasskeyInvalid, err)
+	}
+
+	var passkey *entities.Passkey
+	for _, candidate := range user.passkeys {
+		if bytes.Equal(candidate.CredentialID, credential.ID) {
+			passkey = candidate
+		}
+	}
+	if passkey == nil {
+		return uuid.Nil, ErrPasskeyInvalid
+	}
+
+	if credential.Authenticator.CloneWarning {
+		logs.WarnContext(ctx, "Passkey sign count did not increase", zap.String("passkey_id", passkey.ID.String()))
+		return uuid.Nil, ErrPasskeyCloned
+	}
+
+	if err := p.repository.UpdateSignCount(ctx, passkey.ID, int64(credential.Authenticator.SignCount), credential.Flags.BackupState, time.Now()); err != nil {
+		return uuid.Nil, err
+	}
+
+	return user.id, nil
+}
+
+func (p *passkeys) FindAll(ctx context.Context, accountID uuid.UUID) ([]*entities.Passkey, error) {
+	return p.repository.FindByAccountID(ctx, accountID)
+}
+
+func (p *passkeys) Delete(ctx context.Context, accountID, id uuid.UUID) (bool, error) {
+	return p.repository.Delete(ctx, accountID, id)
+}
+
+func (p *passkeys) loadUser(ctx context.Context, account *entities.Account) (*passkeyUser, error) {
+	stored, err := p.repository.FindByAccountID(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &passkeyUser{
+		id:          account.ID,
+		name:        account.Email,
+		displayName: account.Name,
+		passkeys:    stored,
+	}, nil
+}
+
+func (p *passkeys) saveCeremony(ctx context.Context, accountID uuid.NullUUID, kind entities.CeremonyKind, session *webauthn.SessionData) (uuid.UUID, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	ceremony := entities.NewPasskeyCeremony(accountID, kind, data, time.Now().Add(passkeyCeremonyExpiry))
+	if err := p.repository.CreateCeremony(ctx, ceremony); err != nil {
+		return uuid.Nil, err
+	}
+
+	return ceremony.ID, nil
+}
+
+func (p *passkeys) consumeCeremony(ctx context.Context, id uuid.UUID, kind entities.CeremonyKind) (*entities.PasskeyCeremony, *webauthn.SessionData, error) {
+	ceremony, err := p.repository.ConsumeCeremony(ctx, id, kind)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrPasskeyCeremonyInvalid
+		}
+		return nil, nil, err
+	}
+
+	session := new(webauthn.SessionData)
+	if err := json.Unmarshal(ceremony.SessionData, session); err != nil {
+		return nil, nil, err
+	}
+
+	return ceremony, session, nil
+}
+
+// RunCleanup periodically removes ceremonies that were never completed.
+func (p *passkeys) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := p.repository.DeleteExpiredCeremonies(ctx, time.Now())
+			if err != nil {
+				logs.ErrorContext(ctx, "Error deleting expired passkey ceremonies", err)
+			} else {
+				logs.DebugContext(ctx, "Deleted expired passkey ceremonies", zap.Int64("count", deleted))
+			}
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"ticket-booking/configs"
+	"ticket-booking/entities"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/google/uuid"
+)
+
+const (
+	passkeyRPID   = "localhost"
+	passkeyOrigin = "http://localhost:3000"
+)
+
+// Authenticator data flags, see https://www.w3.org/TR/webauthn-3/#sctn-authenticator-data
+const (
+	flagUserPresent            = 0x01
+	flagUserVerified           = 0x04
+	flagAttestedCredentialData = 0x40
+)
+
+// softwareAuthenticator is a passkey held in memory, answering ceremonies with an ES256 key.
+// Its sign count is set by the test before each assertion.
+type softwareAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftwareAuthenticator(t *testing.T, accountID uuid.UUID) *softwareAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatal(err)
+	}
+
+	return &softwareAuthenticator{key: key, credentialID: credentialID, userHandle: accountID[:]}
+}
+
+// authenticatorData builds the data the authenticator signs; attestedCredential is only included on registration.
+func (a *softwareAuthenticator) authenticatorData(t *testing.T, attestedCredential bool) []byte {
+	t.Helper()
+
+	rpIDHash := sha256.Sum256([]byte(passkeyRPID))
+	data := bytes.NewBuffer(rpIDHash[:])
+
+	flags := byte(flagUserPresent | flagUserVerified)
+	if attestedCredential {
+		flags |= flagAttestedCredentialData
+	}
+	data.WriteByte(flags)
+	_ = binary.Write(data, binary.BigEndian, a.signCount)
+
+	if attestedCredential {
+		publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+			PublicKeyData: webauthncose.PublicKeyData{
+				KeyType:   int64(webauthncose.EllipticKey),
+				Algorithm: int64(webauthncose.AlgES256),
+			},
+			Curve:  int64(webauthncose.P256),
+			XCoord: a.key.X.FillBytes(make([]byte, 32)),
+			YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data.Write(make([]byte, 16)) // AAGUID
+		_ = binary.Write(data, binary.BigEndian, uint16(len(a.credentialID)))
+		data.Write(a.credentialID)
+		data.Write(publicKey)
+	}
+
+	return data.Bytes()
+}
+
+func clientData(t *testing.T, ceremonyType string, challenge protocol.URLEncodedBase64) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]string{
+		"type":      ceremonyType,
+		"challenge": challenge.String(),
+		"origin":    passkeyOrigin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+// register answers the creation options with a "none" attestation.
+func (a *softwareAuthenticator) register(t *testing.T, options *protocol.CredentialCreation) []byte {
+	t.Helper()
+
+	attestationObject, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authenticatorData(t, true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    encode(clientData(t, "webauthn.create", options.Response.Challenge)),
+		"attestationObject": encode(attestationObject),
+	})
+}
+
+// assert answers the request options, signing with the current sign count.
+func (a *softwareAuthenticator) assert(t *testing.T, options *protocol.CredentialAssertion) []byte {
+	t.Helper()
+
+	authenticatorData := a.authenticatorData(t, false)
+	clientDataJSON := clientData(t, "webauthn.get", options.Response.Challenge)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(authenticatorData, clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    encode(clientDataJSON),
+		"authenticatorData": encode(authenticatorData),
+		"signature":         encode(signature),
+		"userHandle":        encode(a.userHandle),
+	})
+}
+
+func (a *softwareAuthenticator) credential(t *testing.T, response map[string]string) []byte {
+	t.Helper()
+
+	credential, err := json.Marshal(map[string]any{
+		"id":       encode(a.credentialID),
+		"rawId":    encode(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return credential
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// fakePasskeyRepository keeps passkeys and ceremonies in memory, with the semantics of the SQL repository.
+type fakePasskeyRepository struct {
+	mu         sync.Mutex
+	passkeys   map[uuid.UUID]*entities.Passkey
+	ceremonies map[uuid.UUID]*entities.PasskeyCeremony
+}
+
+func newFakePasskeyRepository() *fakePasskeyRepository {
+	return &fakePasskeyRepository{
+		passkeys:   make(map[uuid.UUID]*entities.Passkey),
+		ceremonies: make(map[uuid.UUID]*entities.PasskeyCeremony),
+	}
+}
+
+func (r *fakePasskeyRepository) Create(_ context.Context, passkey *entities.Passkey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *passkey
+	r.passkeys[passkey.ID] = &stored
+	return nil
+}
+
+func (r *fakePasskeyRepository) FindByAccountID(_ context.Context, accountID uuid.UUID) ([]*entities.Passkey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var passkeys []*entities.Passkey
+	for _, passkey := range r.passkeys {
+		if passkey.AccountID == accountID {
+			found := *passkey
+			passkeys = append(passkeys, &found)
+		}
+	}
+	return passkeys, nil
+}
+
+func (r *fakePasskeyRepository) UpdateSignCount(_ context.Context, id uuid.UUID, signCount int64, backupState bool, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	passkey, ok := r.passkeys[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	passkey.SignCount = signCount
+	passkey.BackupState = backupState
+	passkey.LastUsedAt = &usedAt
+	return nil
+}
+
+func (r *fakePasskeyRepository) Delete(_ context.Context, accountID, id uuid.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	passkey, ok := r.passkeys[id]
+	if !ok || passkey.AccountID != accountID {
+		return false, nil
+	}
+	delete(r.passkeys, id)
+	return true, nil
+}
+
+func (r *fakePasskeyRepository) CreateCeremony(_ context.Context, ceremony *entities.PasskeyCeremony) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ceremonies[ceremony.ID] = ceremony
+	return nil
+}
+
+func (r *fakePasskeyRepository) ConsumeCeremony(_ context.Context, id uuid.UUID, kind entities.CeremonyKind) (*entities.PasskeyCeremony, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ceremony, ok := r.ceremonies[id]
+	if !ok || ceremony.Kind != kind || !ceremony.ExpiresAt.After(time.Now()) {
+		return nil, sql.ErrNoRows
+	}
+	delete(r.ceremonies, id)
+
+	return ceremony, nil
+}
+
+func (r *fakePasskeyRepository) DeleteExpiredCeremonies(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+// storedPasskey returns the only passkey stored.
+func (r *fakePasskeyRepository) storedPasskey(t *testing.T) *entities.Passkey {
+	t.Helper()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.passkeys) != 1 {
+		t.Fatalf("%d passkeys stored, want 1", len(r.passkeys))
+	}
+	for _, passkey := range r.passkeys {
+		return passkey
+	}
+	return nil
+}
+
+// newRegisteredPasskey sets up the relying party and registers a passkey for a new account.
+func newRegisteredPasskey(t *testing.T) (*passkeys, *fakePasskeyRepository, *softwareAuthenticator, *entities.Account) {
+	t.Helper()
+
+	repository := newFakePasskeyRepository()
+	service, err := NewPasskeys(repository, configs.WebAuthnConfig{RPID: passkeyRPID, RPName: "Ticket Booking"}, passkeyOrigin+"/")
+	if err != nil {
+		t.Fatalf("NewPasskeys: %v", err)
+	}
+
+	account := entities.NewAccount("Jane Doe", "jane.doe@example.com", "hashed")
+	authenticator := newSoftwareAuthenticator(t, account.ID)
+	authenticator.signCount = 1
+
+	ceremonyID, options, err := service.BeginRegistration(context.Background(), account)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+
+	passkey, err := service.FinishRegistration(context.Background(), account, ceremonyID, "Laptop", authenticator.register(t, options))
+	if err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+	if !bytes.Equal(passkey.CredentialID, authenticator.credentialID) || passkey.SignCount != 1 {
+		t.Fatalf("registered passkey = %+v, want credential %x with sign count 1", passkey, authenticator.credentialID)
+	}
+
+	return service, repository, authenticator, account
+}
+
+// login runs a login ceremony with the authenticator's current sign count.
+func login(t *testing.T, service *passkeys, authenticator *softwareAuthenticator) (uuid.UUID, error) {
+	t.Helper()
+
+	ceremonyID, options, err := service.BeginLogin(context.Background())
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+
+	return service.FinishLogin(context.Background(), ceremonyID, authenticator.assert(t, options))
+}
+
+func TestPasskeyLoginStoresSignCount(t *testing.T) {
+	service, repository, authenticator, account := newRegisteredPasskey(t)
+
+	authenticator.signCount = 7
+	accountID, err := login(t, service, authenticator)
+	if err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if accountID != account.ID {
+		t.Errorf("FinishLogin signed in %s, want %s", accountID, account.ID)
+	}
+
+	passkey := repository.storedPasskey(t)
+	if passkey.SignCount != 7 {
+		t.Errorf("stored sign count = %d, want 7", passkey.SignCount)
+	}
+	if passkey.LastUsedAt == nil {
+		t.Error("last use was not recorded")
+	}
+}
+
+func TestPasskeyLoginRejectsNonIncreasingSignCount(t *testing.T) {
+	service, repository, authenticator, _ := newRegisteredPasskey(t)
+
+	authenticator.signCount = 5
+	if _, err := login(t, service, authenticator); err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+
+	for _, signCount := range []uint32{5, 3} {
+		authenticator.signCount = signCount
+		if _, err := login(t, service, authenticator); !errors.Is(err, ErrPasskeyCloned) {
+			t.Errorf("FinishLogin with sign count %d: error = %v, want %v", signCount, err, ErrPasskeyCloned)
+		}
+	}
+
+	if passkey := repository.storedPasskey(t); passkey.SignCount != 5 {
+		t.Errorf("stored sign count = %d after refused logins, want 5", passkey.SignCount)
+	}
+}
+
+func TestPasskeyLoginConsumesCeremony(t *testing.T) {
+	service, _, authenticator, _ := newRegisteredPasskey(t)
+
+	ceremonyID, options, err := service.BeginLogin(context.Background())
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+
+	authenticator.signCount = 2
+	if _, err := service.FinishLogin(context.Background(), ceremonyID, authenticator.assert(t, options)); err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+
+	authenticator.signCount = 3
+	if _, err := service.FinishLogin(context.Background(), ceremonyID, authenticator.assert(t, options)); !errors.Is(err, ErrPasskeyCeremonyInvalid) {
+		t.Errorf("second FinishLogin error = %v, want %v", err, ErrPasskeyCeremonyInvalid)
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"ticket-booking/entities"
+	"ticket-booking/repositories"
+
+	"github.com/google/uuid"
+)
+
+// revocationList answers "has this access token been revoked?" for every authenticated request.
+// Revocations are stored in the database so they reach every replica; answers are cached in process,
+// revoked tokens until they expire and valid tokens for a short ttl.
+type revocationList struct {
+	repository repositories.RevocationRepository
+	ttl        time.Duration
+	mu         sync.RWMutex
+	entries    map[uuid.UUID]revocationEntry
+}
+
+type revocationEntry struct {
+	sessionID uuid.UUID
+	revoked   bool
+	expiresAt time.Time
+}
+
+func newRevocationList(repository repositories.RevocationRepository, ttl time.Duration) *revocationList {
+	return &revocationList{
+		repository: repository,
+		ttl:        ttl,
+		entries:    make(map[uuid.UUID]revocationEntry),
+	}
+}
+
+func (l *revocationList) isRevoked(ctx context.Context, principal *entities.Principal) (bool, error) {
+	now := time.Now()
+
+	l.mu.RLock()
+	entry, ok := l.entries[principal.TokenID]
+	l.mu.RUnlock()
+
+	if ok && entry.expiresAt.After(now) {
+		return entry.revoked, nil
+	}
+
+	revoked, err := l.repository.IsRevoked(ctx, principal.TokenID, principal.SessionID)
+	if err != nil {
+		return false, err
+	}
+
+	expiresAt := now.Add(l.ttl)
+	if revoked || principal.ExpiresAt.Before(expiresAt) {
+		expiresAt = principal.ExpiresAt
+	}
+
+	l.mu.Lock()
+	l.entries[principal.TokenID] = revocationEntry{sessionID: principal.SessionID, revoked: revoked, expiresAt: expiresAt}
+	l.mu.Unlock()
+
+	return revoked, nil
+}
+
+func (l *revocationList) revoke(ctx context.Context, principal *entities.Principal) error {
+	if err := l.repository.Revoke(ctx, principal.TokenID, principal.ExpiresAt); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.entries[principal.TokenID] = revocationEntry{sessionID: principal.SessionID, revoked: true, expiresAt: principal.ExpiresAt}
+	l.mu.Unlock()
+
+	return nil
+}
+
+// revokeSession marks cached tokens of the session as revoked. The session row itself is revoked by the caller.
+func (l *revocationList) revokeSession(sessionID uuid.UUID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for jti, entry := range l.entries {
+		if entry.sessionID == sessionID {
+			entry.revoked = true
+			l.entries[jti] = entry
+		}
+	}
+}
+
+// prune drops cache entries that are no longer valid and expired rows from the database.
+func (l *revocationList) prune(ctx context.Context, now time.Time) (int64, error) {
+	l.mu.Lock()
+	for jti, entry := range l.entries {
+		if entry.expiresAt.Before(now) {
+			delete(l.entries, jti)
+		}
+	}
+	l.mu.Unlock()
+
+	return l.repository.DeleteExpired(ctx, now)
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"ticket-booking/configs"
+	"ticket-booking/configs/logs"
+	"ticket-booking/configs/metrics"
+	"ticket-booking/entities"
+	"ticket-booking/repositories"
+)
+
+// SignInGuard slows down password guessing. Failures are counted per account, in the database so
+// every replica sees the lockout, and per client IP, in process. Past a threshold each further
+// failure doubles the lockout, up to a maximum.
+type SignInGuard interface {
+	// Throttled returns how long the client IP must wait before its next attempt, or zero.
+	Throttled(ip string) time.Duration
+	// Failed records a failed attempt; account is nil when the email matched no account.
+	Failed(ctx context.Context, ip string, account *entities.Account)
+	// Succeeded clears the failures of the IP and the account.
+	Succeeded(ctx context.Context, ip string, account *entities.Account)
+}
+
+type signInGuard struct {
+	repository    repositories.AccountRepository
+	maxFailures   int
+	ipMaxFailures int
+	ipWindow      time.Duration
+	lockout       time.Duration
+	maxLockout    time.Duration
+	mu            sync.Mutex
+	clients       map[string]*clientAttempts
+}
+
+type clientAttempts struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+func NewSignInGuard(repository repositories.AccountRepository, config configs.SignInConfig) *signInGuard {
+	return &signInGuard{
+		repository:    repository,
+		maxFailures:   config.MaxFailures,
+		ipMaxFailures: config.IPMaxFailures,
+		ipWindow:      config.IPWindow,
+		lockout:       config.Lockout,
+		maxLockout:    config.MaxLockout,
+		clients:       make(map[string]*clientAttempts),
+	}
+}
+
+func (g *signInGuard) Throttled(ip string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	client, ok := g.clients[ip]
+	if !ok {
+		return 0
+	}
+
+	return max(time.Until(client.blockedUntil), 0)
+}
+
+func (g *signInGuard) Failed(ctx context.Context, ip string, account *entities.Account) {
+	now := time.Now()
+	metrics.FailedSignIns.Inc()
+
+	g.mu.Lock()
+	client, ok := g.clients[ip]
+	if !ok || now.Sub(client.lastFailure) > g.ipWindow {
+		client = &clientAttempts{}
+		g.clients[ip] = client
+	}
+	client.failures++
+	client.lastFailure = now
+	if client.failures >= g.ipMaxFailures {
+		client.blockedUntil = now.Add(g.backoff(client.failures - g.ipMaxFailures))
+	}
+	g.mu.Unlock()
+
+	// Attempts made while locked are not counted, otherwise anyone could keep an account locked forever
+	if account == nil || account.IsLocked(now) {
+		return
+	}
+
+	failures, err := g.repository.RecordFailedSignIn(ctx, account.ID)
+	if err != nil {
+		logs.ErrorContext(ctx, "SignInGuard.Failed: Failed to record failed sign-in", err)
+		return
+	}
+
+	if failures >= g.maxFailures {
+		if err := g.repository.Lock(ctx, account.ID, now.Add(g.backoff(failures-g.maxFailures))); err != nil {
+			logs.ErrorContext(ctx, "SignInGuard.Failed: Failed to lock account", err)
+		}
+	}
+}
+
+func (g *signInGuard) Succeeded(ctx context.Context, ip string, account *entities.Account) {
+	g.mu.Lock()
+	delete(g.clients, ip)
+	g.mu.Unlock()
+
+	if account.FailedSignIns == 0 && account.LockedUntil == nil {
+		return
+	}
+
+	if err := g.repository.ResetFailedSignIns(ctx, account.ID); err != nil {
+		logs.ErrorContext(ctx, "SignInGuard.Succeeded: Failed to reset failed sign-ins", err)
+	}
+}
+
+// backoff doubles the base lockout for every failure past the threshold, up to the maximum.
+func (g *signInGuard) backoff(excess int) time.Duration {
+	lockout := g.lockout
+	for i := 0; i < excess && lockout < g.maxLockout; i++ {
+		lockout *= 2
+	}
+
+	return min(lockout, g.maxLockout)
+}
+
+// RunCleanup periodically forgets client IPs whose failures are outside the window and no longer blocked.
+func (g *signInGuard) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			g.mu.Lock()
+			for ip, client := range g.clients {
+				if now.Sub(client.lastFailure) > g.ipWindow && now.After(client.blockedUntil) {
+					delete(g.clients, ip)
+				}
+			}
+			g.mu.Unlock()
+		}
+	}
+}
@@ -1,96 +1,130 @@
 package services
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"os"
-	"sync"
 	"time"
 
+	"ticket-booking/configs"
 	"ticket-booking/configs/logs"
 	"ticket-booking/dtos/responses"
+	"ticket-booking/entities"
+	"ticket-booking/repositories"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
 type Tokenization interface {
-	GenerateToken(id string) (*responses.TokenResponse, error)
-	ValidateToken(token string) (bool, error)
-	GenerateRefreshToken(key string) (string, error)
-	VerifyRefreshToken(key, token string) (bool, error)
-	GetAccountID(token string) (uuid.UUID, error)
+	GenerateToken(ctx context.Context, id string, roles []string, device, ipAddress string) (*responses.TokenResponse, error)
+	GenerateSessionToken(ctx context.Context, session *entities.Session, roles []string) (*responses.TokenResponse, error)
+	GenerateRefreshToken(ctx context.Context, sessionID uuid.UUID) (string, error)
+	VerifyRefreshToken(ctx context.Context, token string) (*entities.Session, error)
+	ParseToken(token string) (*entities.Principal, error)
+	GenerateChallengeToken(accountID uuid.UUID) (string, time.Time, error)
+	ParseChallengeToken(token string) (uuid.UUID, error)
+	GenerateActionToken(purpose string, accountID, tokenID uuid.UUID, expiresAt time.Time) (string, error)
+	ParseActionToken(token, purpose string) (uuid.UUID, uuid.UUID, error)
+	IsRevoked(ctx context.Context, principal *entities.Principal) (bool, error)
+	RevokeToken(ctx context.Context, principal *entities.Principal) error
+	RevokeSession(ctx context.Context, accountID, sessionID uuid.UUID) error
+	RevokeAllSessions(ctx context.Context, accountID uuid.UUID) error
+	RevokeOtherSessions(ctx context.Context, principal *entities.Principal) error
+	FindSessions(ctx context.Context, accountID uuid.UUID) ([]*entities.Session, error)
+	JWKS() *responses.JWKSResponse
 }
 
-type tokenization struct {
-	secret            string
-	issuer            string
-	audience          string
-	expiry            time.Duration
-	refreshTokenCache map[string]refreshTokenModel
-	mu                sync.Mutex
-}
+// challengeTokenType marks tokens that only prove the password step of a two-factor sign-in.
+const challengeTokenType = "mfa_challenge"
+
+const challengeTokenExpiry = 5 * time.Minute
+
+var (
+	ErrRefreshTokenInvalid = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrSessionNotFound     = errors.New("session not found")
+)
 
-type refreshTokenModel struct {
-	token  string
-	expiry time.Time
+type tokenization struct {
+	keys          *keySet
+	issuer        string
+	audience      string
+	expiry        time.Duration
+	refreshExpiry time.Duration
+	sessionRepo   repositories.SessionRepository
+	revocations   *revocationList
 }
 
-func NewTokenization() *tokenization {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "default_secret"
-		logs.Warn("JWT_SECRET not set, using default secret")
+func NewTokenization(sessionRepo repositories.SessionRepository, revocationRepo repositories.RevocationRepository, config configs.JWTConfig) (*tokenization, error) {
+	keys, err := loadKeySet(config.KeysDir, config.SigningKeyID, config.Secret)
+	if err != nil {
+		logs.Error("Error loading JWT keys", err)
+		return nil, err
 	}
 
-	issuer := os.Getenv("JWT_ISSUER")
-	if issuer == "" {
-		issuer = "ticket-booking"
-		logs.Warn("JWT_ISSUER not set, using default issuer")
-	}
+	return &tokenization{
+		keys:          keys,
+		issuer:        config.Issuer,
+		audience:      config.Audience,
+		expiry:        config.Expiry,
+		refreshExpiry: config.RefreshExpiry,
+		sessionRepo:   sessionRepo,
+		// The revocation cache TTL bounds how long a logout on another replica can go unnoticed by this one
+		revocations: newRevocationList(revocationRepo, config.RevocationCacheTTL),
+	}, nil
+}
 
-	audience := os.Getenv("JWT_AUDIENCE")
-	if audience == "" {
-		audience = "ticket-booking"
-		logs.Warn("JWT_AUDIENCE not set, using default audience")
-	}
+// GenerateToken starts a new session for the device and issues its first token pair.
+func (t *tokenization) GenerateToken(ctx context.Context, id string, roles []string, device, ipAddress string) (*responses.TokenResponse, error) {
+	ctx, span := configs.StartSpan(ctx, "Tokenization.GenerateToken")
+	defer span.End()
 
-	expiryStr := os.Getenv("JWT_EXPIRY")
-	expiry, err := time.ParseDuration(expiryStr)
+	accountID, err := uuid.Parse(id)
 	if err != nil {
-		expiry = time.Hour * 24
-		logs.Warn("JWT_EXPIRY not set or invalid, using default expiry of 24 hours")
+		logs.ErrorContext(ctx, "Invalid account ID format", err)
+		return nil, fmt.Errorf("account ID format error: %w", err)
 	}
 
-	return &tokenization{
-		secret:            secret,
-		issuer:            issuer,
-		audience:          audience,
-		expiry:            expiry,
-		refreshTokenCache: make(map[string]refreshTokenModel),
+	session := entities.NewSession(accountID, device, ipAddress)
+	if err := t.sessionRepo.Create(ctx, session); err != nil {
+		logs.ErrorContext(ctx, "Error creating session", err)
+		return nil, err
 	}
+
+	return t.GenerateSessionToken(ctx, session, roles)
 }
 
-func (t *tokenization) GenerateToken(id string) (*responses.TokenResponse, error) {
+// GenerateSessionToken issues an access token and the next refresh token of an existing session.
+func (t *tokenization) GenerateSessionToken(ctx context.Context, session *entities.Session, roles []string) (*responses.TokenResponse, error) {
+	ctx, span := configs.StartSpan(ctx, "Tokenization.GenerateSessionToken")
+	defer span.End()
+
 	claims := jwt.MapClaims{
-		"id":  id,
-		"exp": time.Now().Add(t.expiry).Unix(),
-		"aud": t.audience,
-		"iss": t.issuer,
+		"jti":   uuid.New().String(),
+		"sid":   session.ID.String(),
+		"id":    session.AccountID.String(),
+		"roles": roles,
+		"exp":   time.Now().Add(t.expiry).Unix(),
+		"aud":   t.audience,
+		"iss":   t.issuer,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(t.secret))
+	tokenString, err := t.keys.sign(claims)
 	if err != nil {
-		logs.Error("Error signing token", err)
+		logs.ErrorContext(ctx, "Error signing token", err)
 		return nil, err
 	}
 
-	refreshToken, err := t.GenerateRefreshToken(id)
+	refreshToken, err := t.GenerateRefreshToken(ctx, session.ID)
 	if err != nil {
-		logs.Error("Error generating refresh token", err)
+		logs.ErrorContext(ctx, "Error generating refresh token", err)
 		return nil, err
 	}
 
@@ -99,99 +133,117 @@ func (t *tokenization) GenerateToken(id string) (*responses.TokenResponse, error
 	return responses.NewTokenResponse(tokenString, refreshToken, expiryDate), nil
 }
 
-func (t *tokenization) GenerateRefreshToken(key string) (string, error) {
+// GenerateRefreshToken creates a new refresh token in the session's family and stores its hash.
+func (t *tokenization) GenerateRefreshToken(ctx context.Context, sessionID uuid.UUID) (string, error) {
+	ctx, span := configs.StartSpan(ctx, "Tokenization.GenerateRefreshToken")
+	defer span.End()
+
 	salt := make([]byte, 32)
 	_, err := rand.Read(salt)
 	if err != nil {
-		logs.Error("Error generating salt for refresh token", err)
+		logs.ErrorContext(ctx, "Error generating salt for refresh token", err)
 		return "", err
 	}
 
-	refreshToken := base64.StdEncoding.EncodeToString(salt)
-	expiry := time.Now().Add(t.expiry)
+	refreshToken := base64.RawURLEncoding.EncodeToString(salt)
+	model := entities.NewRefreshToken(sessionID, hashToken(refreshToken), time.Now().Add(t.refreshExpiry))
 
-	t.mu.Lock()
-	t.refreshTokenCache[key] = refreshTokenModel{
-		token:  refreshToken,
-		expiry: expiry,
+	if err := t.sessionRepo.CreateRefreshToken(ctx, model); err != nil {
+		logs.ErrorContext(ctx, "Error storing refresh token", err)
+		return "", err
 	}
-	t.mu.Unlock()
 
 	return refreshToken, nil
 }
 
-func (t *tokenization) ValidateToken(token string) (bool, error) {
-	parsedToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			logs.Error("Unexpected signing method", fmt.Errorf("unexpected signing method: %v", token.Header["alg"]))
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(t.secret), nil
-	})
+// VerifyRefreshToken consumes a refresh token and returns the session it belongs to.
+// Presenting a token that was already rotated revokes the whole session, since either
+// the client or an attacker is holding a stolen copy.
+func (t *tokenization) VerifyRefreshToken(ctx context.Context, token string) (*entities.Session, error) {
+	ctx, span := configs.StartSpan(ctx, "Tokenization.VerifyRefreshToken")
+	defer span.End()
 
+	model, err := t.sessionRepo.FindRefreshTokenByHash(ctx, hashToken(token))
 	if err != nil {
-		logs.Error("Error parsing token", err)
-		return false, err
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
 	}
 
-	if claims, ok := parsedToken.Claims.(jwt.MapClaims); ok && parsedToken.Valid {
-		expiry := int64(claims["exp"].(float64))
-		isValid := expiry >= time.Now().Unix()
-		if !isValid {
-			logs.Error("Token has expired", fmt.Errorf("expiry: %d", expiry))
-		}
-		return isValid, nil
+	session, err := t.sessionRepo.FindByID(ctx, model.SessionID)
+	if err != nil {
+		return nil, err
 	}
 
-	logs.Error("Invalid token claims", errors.New("invalid token"))
-	return false, errors.New("invalid token")
-}
+	if session.RevokedAt.Valid {
+		logs.WarnContext(ctx, "Refresh token used on a revoked session", zap.String("session_id", session.ID.String()))
+		return nil, ErrRefreshTokenInvalid
+	}
 
-func (t *tokenization) VerifyRefreshToken(key, token string) (bool, error) {
-	t.mu.Lock()
-	model, exists := t.refreshTokenCache[key]
-	t.mu.Unlock()
+	if model.ExpiresAt.Before(time.Now()) {
+		logs.ErrorContext(ctx, "Expired refresh token", fmt.Errorf("session: %s", session.ID))
+		return nil, ErrRefreshTokenInvalid
+	}
 
-	if !exists {
-		logs.Error("Refresh token not found", fmt.Errorf("key: %s", key))
-		return false, nil
+	rotated, err := t.sessionRepo.RotateRefreshToken(ctx, model.ID)
+	if err != nil {
+		return nil, err
 	}
 
-	if model.token != token || model.expiry.Before(time.Now()) {
-		logs.Error("Invalid or expired refresh token", fmt.Errorf("key: %s", key))
-		return false, nil
+	if !rotated {
+		logs.WarnContext(ctx, "Refresh token reuse detected, revoking session", zap.String("session_id", session.ID.String()))
+		if err := t.sessionRepo.Revoke(ctx, session.ID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
 	}
 
-	t.mu.Lock()
-	delete(t.refreshTokenCache, key)
-	t.mu.Unlock()
+	if err := t.sessionRepo.Touch(ctx, session.ID); err != nil {
+		return nil, err
+	}
 
-	return true, nil
+	return session, nil
 }
 
-func (t *tokenization) GetAccountID(token string) (uuid.UUID, error) {
-	// Parse the token with the signing method validation and secret key.
-	parsedToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		// Ensure the signing method is HMAC
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			err := fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			logs.Error("Unexpected signing method", err)
-			return nil, err
+// RunCleanup periodically deletes expired refresh tokens and revocations until the context is cancelled.
+func (t *tokenization) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := t.sessionRepo.DeleteExpired(ctx, time.Now())
+			if err != nil {
+				logs.ErrorContext(ctx, "Error deleting expired refresh tokens", err)
+			} else {
+				logs.DebugContext(ctx, "Deleted expired refresh tokens", zap.Int64("count", deleted))
+			}
+
+			pruned, err := t.revocations.prune(ctx, time.Now())
+			if err != nil {
+				logs.ErrorContext(ctx, "Error deleting expired revocations", err)
+			} else {
+				logs.DebugContext(ctx, "Deleted expired revocations", zap.Int64("count", pruned))
+			}
 		}
-		return []byte(t.secret), nil
-	})
+	}
+}
 
-	// Handle parsing errors
+// ParseToken verifies the access token once and returns the principal described by its claims.
+func (t *tokenization) ParseToken(token string) (*entities.Principal, error) {
+	claims, err := t.parseClaims(token)
 	if err != nil {
-		logs.Error("Error parsing token", err)
-		return uuid.Nil, fmt.Errorf("token parsing error: %w", err)
+		return nil, err
 	}
 
-	// Ensure claims are in the expected format and token is valid
-	claims, ok := parsedToken.Claims.(jwt.MapClaims)
-	if !ok || !parsedToken.Valid {
-		logs.Error("Invalid token claims or token not valid", nil)
-		return uuid.Nil, errors.New("invalid token: claims not valid")
+	if tokenType, _ := claims["typ"].(string); tokenType != "" {
+		err := fmt.Errorf("unexpected token type: %s", tokenType)
+		logs.Error("Invalid token claims", err)
+		return nil, err
 	}
 
 	// Extract account ID from claims and ensure it’s a string
@@ -199,15 +251,240 @@ func (t *tokenization) GetAccountID(token string) (uuid.UUID, error) {
 	if !ok {
 		err := errors.New("account ID claim missing or not a string")
 		logs.Error("Invalid token claims", err)
-		return uuid.Nil, err
+		return nil, err
 	}
 
-	// Convert account ID to UUID format
-	accountUUID, err := uuid.Parse(accountId)
+	accountID, err := uuid.Parse(accountId)
 	if err != nil {
 		logs.Error("Invalid account ID format", err)
-		return uuid.Nil, fmt.Errorf("account ID format error: %w", err)
+		return nil, fmt.Errorf("account ID format error: %w", err)
+	}
+
+	jti, _ := claims["jti"].(string)
+	tokenID, err := uuid.Parse(jti)
+	if err != nil {
+		logs.Error("Invalid token ID claim", err)
+		return nil, fmt.Errorf("token ID format error: %w", err)
+	}
+
+	sid, _ := claims["sid"].(string)
+	sessionID, err := uuid.Parse(sid)
+	if err != nil {
+		logs.Error("Invalid session ID claim", err)
+		return nil, fmt.Errorf("session ID format error: %w", err)
+	}
+
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		logs.Error("Invalid expiry claim", err)
+		return nil, errors.New("token expiry claim missing")
 	}
 
-	return accountUUID, nil
+	rawRoles, _ := claims["roles"].([]interface{})
+	roles := make([]string, 0, len(rawRoles))
+	for _, rawRole := range rawRoles {
+		role, ok := rawRole.(string)
+		if !ok {
+			err := errors.New("roles claim contains a non-string value")
+			logs.Error("Invalid token claims", err)
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return entities.NewPrincipal(accountID, roles, sessionID, tokenID, expiresAt.Time), nil
+}
+
+// GenerateChallengeToken issues a short-lived token proving the account passed the password step.
+// It is exchanged for a session once a valid second factor is presented.
+func (t *tokenization) GenerateChallengeToken(accountID uuid.UUID) (string, time.Time, error) {
+	expiresAt := time.Now().Add(challengeTokenExpiry)
+
+	token, err := t.GenerateActionToken(challengeTokenType, accountID, uuid.New(), expiresAt)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+// ParseChallengeToken verifies a challenge token and returns the account it was issued for.
+func (t *tokenization) ParseChallengeToken(token string) (uuid.UUID, error) {
+	accountID, _, err := t.ParseActionToken(token, challengeTokenType)
+	return accountID, err
+}
+
+// GenerateActionToken signs a token that can only be used for the given purpose, such as
+// verifying an email address. It is never accepted as an access token.
+func (t *tokenization) GenerateActionToken(purpose string, accountID, tokenID uuid.UUID, expiresAt time.Time) (string, error) {
+	claims := jwt.MapClaims{
+		"typ": purpose,
+		"jti": tokenID.String(),
+		"id":  accountID.String(),
+		"exp": expiresAt.Unix(),
+		"aud": t.audience,
+		"iss": t.issuer,
+	}
+
+	token, err := t.keys.sign(claims)
+	if err != nil {
+		logs.Error("Error signing action token", err)
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ParseActionToken verifies a token issued by GenerateActionToken for the purpose
+// and returns the account and token IDs.
+func (t *tokenization) ParseActionToken(token, purpose string) (uuid.UUID, uuid.UUID, error) {
+	claims, err := t.parseClaims(token)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	if tokenType, _ := claims["typ"].(string); tokenType != purpose {
+		err := fmt.Errorf("unexpected token type: %s", tokenType)
+		logs.Error("Invalid action token", err)
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	accountId, _ := claims["id"].(string)
+	accountID, err := uuid.Parse(accountId)
+	if err != nil {
+		logs.Error("Invalid account ID format", err)
+		return uuid.Nil, uuid.Nil, fmt.Errorf("account ID format error: %w", err)
+	}
+
+	jti, _ := claims["jti"].(string)
+	tokenID, err := uuid.Parse(jti)
+	if err != nil {
+		logs.Error("Invalid token ID claim", err)
+		return uuid.Nil, uuid.Nil, fmt.Errorf("token ID format error: %w", err)
+	}
+
+	return accountID, tokenID, nil
+}
+
+// IsRevoked reports whether the token was logged out or belongs to a revoked session.
+func (t *tokenization) IsRevoked(ctx context.Context, principal *entities.Principal) (bool, error) {
+	ctx, span := configs.StartSpan(ctx, "Tokenization.IsRevoked")
+	defer span.End()
+
+	return t.revocations.isRevoked(ctx, principal)
+}
+
+// RevokeToken logs out the token and the session it was issued for.
+func (t *tokenization) RevokeToken(ctx context.Context, principal *entities.Principal) error {
+	ctx, span := configs.StartSpan(ctx, "Tokenization.RevokeToken")
+	defer span.End()
+
+	if err := t.revocations.revoke(ctx, principal); err != nil {
+		return err
+	}
+
+	if err := t.sessionRepo.Revoke(ctx, principal.SessionID); err != nil {
+		return err
+	}
+	t.revocations.revokeSession(principal.SessionID)
+
+	return nil
+}
+
+// RevokeSession logs out one of the account's sessions.
+func (t *tokenization) RevokeSession(ctx context.Context, accountID, sessionID uuid.UUID) error {
+	ctx, span := configs.StartSpan(ctx, "Tokenization.RevokeSession")
+	defer span.End()
+
+	session, err := t.sessionRepo.FindByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+
+	if session.AccountID != accountID {
+		return ErrSessionNotFound
+	}
+
+	if err := t.sessionRepo.Revoke(ctx, session.ID); err != nil {
+		return err
+	}
+	t.revocations.revokeSession(session.ID)
+
+	return nil
+}
+
+// RevokeAllSessions logs the account out of every device.
+func (t *tokenization) RevokeAllSessions(ctx context.Context, accountID uuid.UUID) error {
+	ctx, span := configs.StartSpan(ctx, "Tokenization.RevokeAllSessions")
+	defer span.End()
+
+	return t.revokeSessions(ctx, accountID, uuid.Nil)
+}
+
+// RevokeOtherSessions logs the account out of every device but the caller's.
+func (t *tokenization) RevokeOtherSessions(ctx context.Context, principal *entities.Principal) error {
+	ctx, span := configs.StartSpan(ctx, "Tokenization.RevokeOtherSessions")
+	defer span.End()
+
+	return t.revokeSessions(ctx, principal.AccountID, principal.SessionID)
+}
+
+func (t *tokenization) revokeSessions(ctx context.Context, accountID, except uuid.UUID) error {
+	sessionIDs, err := t.sessionRepo.RevokeAllByAccountID(ctx, accountID, except)
+	if err != nil {
+		return err
+	}
+
+	for _, sessionID := range sessionIDs {
+		t.revocations.revokeSession(sessionID)
+	}
+
+	return nil
+}
+
+func (t *tokenization) FindSessions(ctx context.Context, accountID uuid.UUID) ([]*entities.Session, error) {
+	ctx, span := configs.StartSpan(ctx, "Tokenization.FindSessions")
+	defer span.End()
+
+	return t.sessionRepo.FindActiveByAccountID(ctx, accountID)
+}
+
+// JWKS returns the public keys tokens can be verified with.
+func (t *tokenization) JWKS() *responses.JWKSResponse {
+	return t.keys.jwks()
+}
+
+// parseClaims parses and validates the token, returning its claims.
+func (t *tokenization) parseClaims(token string) (jwt.MapClaims, error) {
+	// Parse the token, resolving the verification key from its kid header. Tokens signed with the same
+	// key for another audience or by another issuer are refused, as are tokens that never expire.
+	parsedToken, err := jwt.Parse(token, t.keys.verificationKey,
+		jwt.WithAudience(t.audience),
+		jwt.WithIssuer(t.issuer),
+		jwt.WithExpirationRequired(),
+	)
+
+	// Handle parsing errors
+	if err != nil {
+		logs.Error("Error parsing token", err)
+		return nil, fmt.Errorf("token parsing error: %w", err)
+	}
+
+	// Ensure claims are in the expected format and token is valid
+	claims, ok := parsedToken.Claims.(jwt.MapClaims)
+	if !ok || !parsedToken.Valid {
+		logs.Error("Invalid token claims or token not valid", nil)
+		return nil, errors.New("invalid token: claims not valid")
+	}
+
+	return claims, nil
+}
+
+// hashToken returns the digest stored in place of a random secret token (refresh tokens, API keys).
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"ticket-booking/configs"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestParseTokenChecksAudienceIssuerAndExpiry(t *testing.T) {
+	tokenization, err := NewTokenization(nil, nil, configs.JWTConfig{
+		Secret:   "test-secret",
+		Issuer:   "ticket-booking",
+		Audience: "ticket-booking",
+		Expiry:   time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewTokenization: %v", err)
+	}
+
+	claims := func(override jwt.MapClaims) jwt.MapClaims {
+		claims := jwt.MapClaims{
+			"jti":   uuid.NewString(),
+			"sid":   uuid.NewString(),
+			"id":    uuid.NewString(),
+			"roles": []string{"customer"},
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"aud":   "ticket-booking",
+			"iss":   "ticket-booking",
+		}
+		for name, value := range override {
+			if value == nil {
+				delete(claims, name)
+			} else {
+				claims[name] = value
+			}
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name     string
+		override jwt.MapClaims
+		valid    bool
+	}{
+		{name: "valid", valid: true},
+		{name: "another audience", override: jwt.MapClaims{"aud": "another-service"}},
+		{name: "no audience", override: jwt.MapClaims{"aud": nil}},
+		{name: "another issuer", override: jwt.MapClaims{"iss": "another-issuer"}},
+		{name: "no expiry", override: jwt.MapClaims{"exp": nil}},
+		{name: "expired", override: jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			token, err := tokenization.keys.sign(claims(test.override))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = tokenization.ParseToken(token)
+			if test.valid && err != nil {
+				t.Errorf("ParseToken: %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Error("ParseToken accepted the token")
+			}
+		})
+	}
+}
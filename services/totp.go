@@ -0,0 +1,155 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"ticket-booking/configs/logs"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// TOTP implements time-based one-time passwords (RFC 6238) compatible with common authenticator apps:
+// HMAC-SHA1, 6 digits and a 30 second period.
+type TOTP interface {
+	GenerateSecret() (string, error)
+	URI(secret, accountName string) string
+	QRCode(uri string) ([]byte, error)
+	Validate(secret, code string, lastStep int64) (int64, bool)
+	GenerateRecoveryCodes() ([]string, error)
+	HashRecoveryCode(code string) string
+}
+
+type totp struct {
+	issuer string
+	period int64
+	digits int
+	// skew is the number of periods accepted before and after the current one, for clock drift.
+	skew int64
+}
+
+const (
+	totpSecretSize    = 20
+	recoveryCodeCount = 10
+	recoveryCodeBytes = 10
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func NewTOTP(issuer string) *totp {
+	return &totp{
+		issuer: issuer,
+		period: 30,
+		digits: 6,
+		skew:   1,
+	}
+}
+
+// GenerateSecret returns a new random secret, base32 encoded as authenticator apps expect.
+func (t *totp) GenerateSecret() (string, error) {
+	secret := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		logs.Error("Failed to generate TOTP secret", err)
+		return "", fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+
+	return base32NoPadding.EncodeToString(secret), nil
+}
+
+// URI returns the otpauth:// URI used to enroll the secret in an authenticator app.
+func (t *totp) URI(secret, accountName string) string {
+	label := url.PathEscape(t.issuer + ":" + accountName)
+
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", t.issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(t.digits))
+	query.Set("period", fmt.Sprint(t.period))
+
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, query.Encode())
+}
+
+// QRCode renders the URI as a PNG QR code.
+func (t *totp) QRCode(uri string) ([]byte, error) {
+	return qrcode.Encode(uri, qrcode.Medium, 256)
+}
+
+// Validate checks the code against the secret and returns the time step it matched.
+// Steps up to lastStep are rejected so a code cannot be replayed.
+func (t *totp) Validate(secret, code string, lastStep int64) (int64, bool) {
+	key, err := base32NoPadding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		logs.Error("Invalid TOTP secret", err)
+		return 0, false
+	}
+
+	code = strings.TrimSpace(code)
+	current := time.Now().Unix() / t.period
+
+	for step := current - t.skew; step <= current+t.skew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(t.code(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// GenerateRecoveryCodes returns single-use codes accepted in place of a TOTP code.
+func (t *totp) GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(raw); err != nil {
+			logs.Error("Failed to generate recovery code", err)
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		encoded := strings.ToLower(base32NoPadding.EncodeToString(raw))
+		codes = append(codes, encoded[:8]+"-"+encoded[8:])
+	}
+
+	return codes, nil
+}
+
+// HashRecoveryCode returns the digest stored in place of a recovery code.
+// Codes carry 80 random bits, so a fast hash is enough.
+func (t *totp) HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func (t *totp) code(key []byte, step int64) string {
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < t.digits; i++ {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", t.digits, value%modulo)
+}
@@ -1,14 +1,31 @@
+-- Snapshot of the complete schema, for reference. The schema is created and upgraded by the embedded
+-- migrations in migrations/ ("ticket-booking migrate up"); a database created from this file can be
+-- brought under their control with "ticket-booking migrate force 15".
+
 CREATE TABLE accounts (
     id UUID PRIMARY KEY,
-    name VARCHAR2(100) NOT NULL,
+    name VARCHAR(100) NOT NULL,
     email VARCHAR(255) NOT NULL UNIQUE,
+    email_verified_at TIMESTAMP,
+    pending_email VARCHAR(255),
     password VARCHAR(255) NOT NULL,
+    roles TEXT[] NOT NULL DEFAULT '{customer}',
+    totp_secret VARCHAR(64),
+    totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    totp_last_step BIGINT NOT NULL DEFAULT 0,
+    failed_sign_ins INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP,
     created_at TIMESTAMP NOT NULL,
-    updated_at TIMESTAMP NOT NULL
+    updated_at TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP
 );
 
+CREATE UNIQUE INDEX accounts_email_lower_idx ON accounts (lower(email));
+CREATE INDEX accounts_locked_until_idx ON accounts (locked_until) WHERE locked_until IS NOT NULL;
+
 CREATE TABLE events (
     id SERIAL PRIMARY KEY,
+    public_id UUID NOT NULL UNIQUE,
     title VARCHAR(255) NOT NULL,
     location VARCHAR(255) NOT NULL,
     date TIMESTAMP NOT NULL,
@@ -18,9 +35,134 @@ CREATE TABLE events (
 
 CREATE TABLE tickets (
     id SERIAL PRIMARY KEY,
+    public_id UUID NOT NULL UNIQUE,
     event_id BIGINT NOT NULL REFERENCES events(id),
     account_id UUID NOT NULL REFERENCES accounts(id),
     entered BOOLEAN NOT NULL,
     created_at TIMESTAMP NOT NULL,
     updated_at TIMESTAMP NOT NULL
 );
+
+CREATE INDEX tickets_event_id_idx ON tickets (event_id);
+CREATE INDEX tickets_account_id_idx ON tickets (account_id);
+
+CREATE TABLE sessions (
+    id UUID PRIMARY KEY,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    device VARCHAR(255) NOT NULL,
+    ip_address VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX sessions_account_id_idx ON sessions (account_id);
+
+CREATE TABLE refresh_tokens (
+    id UUID PRIMARY KEY,
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    rotated_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX refresh_tokens_expires_at_idx ON refresh_tokens (expires_at);
+CREATE INDEX refresh_tokens_session_id_idx ON refresh_tokens (session_id);
+
+CREATE TABLE revoked_tokens (
+    jti UUID PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX revoked_tokens_expires_at_idx ON revoked_tokens (expires_at);
+
+CREATE TABLE recovery_codes (
+    id UUID PRIMARY KEY,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX recovery_codes_account_id_idx ON recovery_codes (account_id);
+
+CREATE TABLE account_tokens (
+    id UUID PRIMARY KEY,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    purpose VARCHAR(32) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX account_tokens_expires_at_idx ON account_tokens (expires_at);
+CREATE INDEX account_tokens_account_id_idx ON account_tokens (account_id, purpose, created_at);
+
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX api_keys_account_id_idx ON api_keys (account_id);
+
+CREATE TABLE oidc_states (
+    state VARCHAR(64) PRIMARY KEY,
+    provider VARCHAR(32) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX oidc_states_expires_at_idx ON oidc_states (expires_at);
+
+CREATE TABLE account_identities (
+    id UUID PRIMARY KEY,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    provider VARCHAR(32) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX account_identities_account_id_idx ON account_identities (account_id);
+
+CREATE TABLE passkeys (
+    id UUID PRIMARY KEY,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    credential_id BYTEA NOT NULL UNIQUE,
+    public_key BYTEA NOT NULL,
+    attestation_type VARCHAR(32) NOT NULL,
+    aaguid BYTEA,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    transports TEXT[] NOT NULL DEFAULT '{}',
+    backup_eligible BOOLEAN NOT NULL DEFAULT FALSE,
+    backup_state BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP
+);
+
+CREATE INDEX passkeys_account_id_idx ON passkeys (account_id);
+
+CREATE TABLE passkey_ceremonies (
+    id UUID PRIMARY KEY,
+    account_id UUID REFERENCES accounts(id) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL,
+    session_data JSONB NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX passkey_ceremonies_expires_at_idx ON passkey_ceremonies (expires_at);
+CREATE INDEX passkey_ceremonies_account_id_idx ON passkey_ceremonies (account_id);